@@ -0,0 +1,69 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+type BenchPerson struct {
+	ID      int       `xmlrpc:"id"`
+	Name    string    `xmlrpc:"name"`
+	Email   string    `xmlrpc:"email"`
+	Balance float64   `xmlrpc:"balance"`
+	Active  bool      `xmlrpc:"active"`
+	Created time.Time `xmlrpc:"created"`
+	Tags    []string  `xmlrpc:"tags"`
+}
+
+// benchmarkDecode decodes the fixture testdata/name into a new reply,
+// as returned by newReply, on each iteration.
+func benchmarkDecode(b *testing.B, name string, newReply func() interface{}) {
+	data, err := ioutil.ReadFile("testdata/" + name)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err := DecodeClientResponse(bytes.NewReader(data), newReply()); err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := DecodeClientResponse(bytes.NewReader(data), newReply()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeSmall(b *testing.B) {
+	benchmarkDecode(b, "bench_small.xml", func() interface{} { return new(struct{ N int }) })
+}
+
+func BenchmarkDecodeStruct(b *testing.B) {
+	benchmarkDecode(b, "bench_struct.xml", func() interface{} { return new(struct{ P BenchPerson }) })
+}
+
+func BenchmarkDecodeArray(b *testing.B) {
+	benchmarkDecode(b, "bench_array.xml", func() interface{} { return new(struct{ People []BenchPerson }) })
+}
+
+func BenchmarkDecodeNested(b *testing.B) {
+	benchmarkDecode(b, "bench_nested.xml", func() interface{} { return new(struct{ Tree interface{} }) })
+}
+
+func BenchmarkDecodeArrayInterface(b *testing.B) {
+	benchmarkDecode(b, "bench_array.xml", func() interface{} { return new([]interface{}) })
+}
+
+func BenchmarkDecodeStringMap(b *testing.B) {
+	benchmarkDecode(b, "bench_strings.xml", func() interface{} { return new(struct{ M map[string]string }) })
+}
+
+func BenchmarkDecodeInterfaceMap(b *testing.B) {
+	benchmarkDecode(b, "bench_strings.xml", func() interface{} { return new(struct{ M map[string]interface{} }) })
+}
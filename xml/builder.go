@@ -0,0 +1,104 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"strconv"
+	"time"
+)
+
+// RequestBuilder builds a methodCall param by param, for requests whose
+// params have no Go types, such as those of tools taking them as input:
+//
+//	data, err := xml.NewRequestBuilder("blogger.getRecentPosts").
+//		AddString("blog").
+//		AddStruct(map[string]interface{}{"user": "ivan", "count": 10}).
+//		Bytes()
+//
+// The first error, such as for a value which can't be encoded, is
+// returned by Bytes.
+type RequestBuilder struct {
+	encoder *Encoder
+	method  string
+	params  string
+	n       int
+	err     error
+}
+
+// NewRequestBuilder returns a RequestBuilder for a call of method, which
+// encodes params with opts.
+func NewRequestBuilder(method string, opts ...Option) *RequestBuilder {
+	return &RequestBuilder{encoder: NewEncoder(opts...), method: method}
+}
+
+// Add adds a param holding v, encoded as the fields of a request struct
+// are.
+func (b *RequestBuilder) Add(v interface{}) *RequestBuilder {
+	if b.err != nil {
+		return b
+	}
+	xml, err := b.encoder.rpc2XML(v)
+	if err != nil {
+		b.err = encodeError("params["+strconv.Itoa(b.n)+"]", err)
+		return b
+	}
+	b.params += "<param>" + xml + "</param>"
+	b.n++
+	return b
+}
+
+// AddInt adds an <int> param.
+func (b *RequestBuilder) AddInt(n int) *RequestBuilder {
+	return b.Add(n)
+}
+
+// AddDouble adds a <double> param.
+func (b *RequestBuilder) AddDouble(f float64) *RequestBuilder {
+	return b.Add(f)
+}
+
+// AddString adds a <string> param.
+func (b *RequestBuilder) AddString(s string) *RequestBuilder {
+	return b.Add(s)
+}
+
+// AddBool adds a <boolean> param.
+func (b *RequestBuilder) AddBool(v bool) *RequestBuilder {
+	return b.Add(v)
+}
+
+// AddTime adds a <dateTime.iso8601> param.
+func (b *RequestBuilder) AddTime(t time.Time) *RequestBuilder {
+	return b.Add(t)
+}
+
+// AddBase64 adds a <base64> param.
+func (b *RequestBuilder) AddBase64(data []byte) *RequestBuilder {
+	return b.Add(data)
+}
+
+// AddStruct adds a <struct> param, with a member per entry of members,
+// sorted by name.
+func (b *RequestBuilder) AddStruct(members map[string]interface{}) *RequestBuilder {
+	return b.Add(members)
+}
+
+// AddArray adds an <array> param holding items.
+func (b *RequestBuilder) AddArray(items ...interface{}) *RequestBuilder {
+	if items == nil {
+		items = []interface{}{}
+	}
+	return b.Add(items)
+}
+
+// Bytes returns the methodCall, or the first error met adding params.
+func (b *RequestBuilder) Bytes() ([]byte, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	e := b.encoder
+	return e.document(e.root("methodCall") + "<methodName>" + escapeString(b.method) + "</methodName>" +
+		"<params>" + b.params + "</params></methodCall>")
+}
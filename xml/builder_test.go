@@ -0,0 +1,59 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestRequestBuilder(t *testing.T) {
+	data, err := NewRequestBuilder("blogger.getRecentPosts").
+		AddString("blog").
+		AddStruct(map[string]interface{}{"user": "ivan", "count": 10, "tags": []string{"go"}}).
+		Bytes()
+	if err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	expected := "<methodCall><methodName>blogger.getRecentPosts</methodName><params><param><value><string>blog</string></value></param><param><value><struct><member><name>count</name><value><int>10</int></value></member><member><name>tags</name><value><array><data><value><string>go</string></value></data></array></value></member><member><name>user</name><value><string>ivan</string></value></member></struct></value></param></params></methodCall>"
+	if string(data) != expected {
+		t.Error("Expected", expected)
+		t.Error("Got", string(data))
+	}
+
+	var args struct {
+		Blog    string
+		Options struct {
+			User  string   `xmlrpc:"user"`
+			Count int      `xmlrpc:"count"`
+			Tags  []string `xmlrpc:"tags"`
+		}
+	}
+	method, err := DecodeServerRequest(bytes.NewReader(data), &args)
+	if err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if method != "blogger.getRecentPosts" || args.Blog != "blog" || args.Options.User != "ivan" ||
+		args.Options.Count != 10 || !reflect.DeepEqual(args.Options.Tags, []string{"go"}) {
+		t.Errorf("wrong request: %s %+v", method, args)
+	}
+}
+
+func TestRequestBuilderError(t *testing.T) {
+	_, err := NewRequestBuilder("Some.Method", WithInvalidChars(InvalidCharsReject)).
+		AddInt(1).
+		AddArray(1, "invalid \x01 char").
+		AddDouble(math.Pi).
+		Bytes()
+	encodeErr, ok := err.(*EncodeError)
+	if !ok {
+		t.Fatalf("expected *EncodeError, but got %v", err)
+	}
+	if encodeErr.Path != "params[1][1]" {
+		t.Errorf("expected the path of the invalid item, but got %q", encodeErr.Path)
+	}
+}
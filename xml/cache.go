@@ -0,0 +1,206 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Cache stores the responses of a Client, as set up with WithCache and
+// WithCacheStore. Implementations must be safe for concurrent use.
+type Cache interface {
+	// Get returns the response stored under key, if it hasn't expired.
+	Get(key string) ([]byte, bool)
+	// Set stores response under key for ttl.
+	Set(key string, response []byte, ttl time.Duration)
+}
+
+// WithCache makes a Client store the responses to calls of methods for
+// ttl, and answer identical calls, with the same method and parameters,
+// from them. All methods are cached if none are given. Only the methods
+// without side effects should be cached. Faults and failed HTTP requests
+// aren't cached. The responses are held in a MemoryCache of the Client,
+// unless another Cache is given with WithCacheStore. See also NoCache and
+// Client.Invalidate.
+func WithCache(ttl time.Duration, methods ...string) Option {
+	return func(o *options) {
+		o.cache = true
+		o.cacheTTL = ttl
+		o.cacheMethods = nil
+		if len(methods) > 0 {
+			o.cacheMethods = make(map[string]bool, len(methods))
+			for _, method := range methods {
+				o.cacheMethods[method] = true
+			}
+		}
+	}
+}
+
+// WithCacheStore makes the cache set up with WithCache store the
+// responses in cache, such as to share them between clients or keep them
+// out of process. It has no effect without WithCache.
+func WithCacheStore(cache Cache) Option {
+	return func(o *options) {
+		o.cacheStore = cache
+	}
+}
+
+// MemoryCache is a Cache holding the responses in memory. Expired
+// responses are removed when they are looked up, or as more are stored.
+type MemoryCache struct {
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	sweepAt int // number of entries at which expired ones are removed
+}
+
+// minSweep is the least number of entries of a MemoryCache at which the
+// expired ones are removed.
+const minSweep = 64
+
+type cacheEntry struct {
+	response []byte
+	expires  time.Time
+}
+
+// NewMemoryCache returns an empty MemoryCache.
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: make(map[string]cacheEntry), sweepAt: minSweep}
+}
+
+// Get returns the response stored under key, if it hasn't expired.
+func (c *MemoryCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return entry.response, true
+}
+
+// Set stores response under key for ttl. Whenever the number of entries
+// has doubled since expired ones were last removed, they are removed, so
+// that the cache doesn't grow with the responses no longer looked up.
+func (c *MemoryCache) Set(key string, response []byte, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	c.entries[key] = cacheEntry{response: response, expires: now.Add(ttl)}
+	if len(c.entries) < c.sweepAt {
+		return
+	}
+	for key, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, key)
+		}
+	}
+	if c.sweepAt = 2 * len(c.entries); c.sweepAt < minSweep {
+		c.sweepAt = minSweep
+	}
+}
+
+type noCacheKey struct{}
+
+// NoCache returns a copy of ctx making the calls of a Client made with it
+// bypass the cache set up with WithCache.
+func NoCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noCacheKey{}, true)
+}
+
+// clientCache is the state of the cache of a Client: the calls answered
+// from the cache or not, the Cache holding the responses, and the
+// generation of the responses of each method, which Invalidate increments
+// so that the cached responses are no longer found.
+type clientCache struct {
+	hits, misses int64
+	store        Cache
+
+	mu          sync.Mutex
+	generations map[string]int
+}
+
+// CacheStats returns the number of calls answered from the cache set up
+// with WithCache, and the number of those which weren't.
+func (c *Client) CacheStats() (hits, misses int64) {
+	if c.cache == nil {
+		return 0, 0
+	}
+	return atomic.LoadInt64(&c.cache.hits), atomic.LoadInt64(&c.cache.misses)
+}
+
+// Invalidate discards the cached responses to the calls of method.
+func (c *Client) Invalidate(method string) {
+	if c.cache == nil {
+		return
+	}
+	c.cache.mu.Lock()
+	c.cache.generations[method]++
+	c.cache.mu.Unlock()
+}
+
+// cached reports whether the response to a call of method with ctx may
+// be cached.
+func (c *Client) cached(ctx context.Context, method string) bool {
+	o := c.decoder.opts
+	return c.cache != nil && (o.cacheMethods == nil || o.cacheMethods[method]) &&
+		ctx.Value(noCacheKey{}) == nil
+}
+
+// cacheKey returns the key of the response to a call of method with the
+// request body.
+func (c *Client) cacheKey(method string, body []byte) string {
+	c.cache.mu.Lock()
+	generation := c.cache.generations[method]
+	c.cache.mu.Unlock()
+	sum := sha256.Sum256(body)
+	return method + ":" + strconv.Itoa(generation) + ":" + hex.EncodeToString(sum[:])
+}
+
+// cachedCall sends the request body of a call to method, unless its
+// response is cached, and decodes the response into reply. If raw isn't
+// nil, the response is stored in it.
+func (c *Client) cachedCall(ctx context.Context, method string, body []byte, reply interface{}, raw *[]byte) error {
+	o := c.decoder.opts
+	d := c.decoder.withContext(ctx)
+	key := c.cacheKey(method, body)
+	if response, ok := c.cache.store.Get(key); ok {
+		atomic.AddInt64(&c.cache.hits, 1)
+		if raw != nil {
+			// The cached response is shared by the calls.
+			*raw = append([]byte(nil), response...)
+		}
+		return d.decodeResponse(bytes.NewReader(response), reply)
+	}
+	atomic.AddInt64(&c.cache.misses, 1)
+
+	resp, err := c.post(ctx, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	response, err := o.readLimited(resp.Body)
+	if err != nil {
+		return err
+	}
+	if raw != nil {
+		*raw = append([]byte(nil), response...)
+	}
+	if err := d.decodeResponse(bytes.NewReader(response), reply); err != nil {
+		return err
+	}
+	c.cache.store.Set(key, response, o.cacheTTL)
+	return nil
+}
@@ -0,0 +1,233 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClientCache(t *testing.T) {
+	var hits int64
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&hits, 1)
+		var args Service1Request
+		method, err := DecodeServerRequest(r.Body, &args)
+		if err != nil {
+			t.Error(err)
+		}
+		if method == "Fail" {
+			w.Write(EncodeFault(Fault{Code: 1, String: "Failed"}))
+			return
+		}
+		buf, _ := EncodeServerResponse(&Service1Response{args.A * args.B})
+		w.Write(buf)
+	}))
+	defer s.Close()
+
+	c := NewClient(s.URL, WithCache(time.Minute, "Multiply", "Fail"))
+	call := func(method string, a, b int) (int, error) {
+		var res Service1Response
+		err := c.Call(context.Background(), method, &Service1Request{a, b}, &res)
+		return res.Result, err
+	}
+
+	for i := 0; i < 2; i++ {
+		if result, err := call("Multiply", 4, 2); err != nil || result != 8 {
+			t.Errorf("expected 8, but got %d, %v", result, err)
+		}
+	}
+	if n := atomic.LoadInt64(&hits); n != 1 {
+		t.Errorf("expected 1 server hit, but got %d", n)
+	}
+
+	if result, err := call("Multiply", 4, 3); err != nil || result != 12 {
+		t.Errorf("expected 12, but got %d, %v", result, err)
+	}
+	if n := atomic.LoadInt64(&hits); n != 2 {
+		t.Errorf("expected a server hit for other params, but got %d hits", n)
+	}
+
+	// Methods which aren't listed and faults aren't cached
+	for i := 0; i < 2; i++ {
+		call("Other", 4, 2)
+		if _, err := call("Fail", 4, 2); err == nil {
+			t.Error("expected fault, but got nil")
+		}
+	}
+	if n := atomic.LoadInt64(&hits); n != 6 {
+		t.Errorf("expected 6 server hits, but got %d", n)
+	}
+}
+
+func TestClientCacheStore(t *testing.T) {
+	var hits int64
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&hits, 1)
+		buf, _ := EncodeServerResponse(&Service1Response{8})
+		w.Write(buf)
+	}))
+	defer s.Close()
+
+	// Clients have a cache of their own, unless they are given one.
+	store := NewMemoryCache()
+	clients := []*Client{
+		NewClient(s.URL, WithCache(time.Minute)),
+		NewClient(s.URL, WithCache(time.Minute)),
+		NewClient(s.URL, WithCache(time.Minute), WithCacheStore(store)),
+		NewClient(s.URL, WithCacheStore(store), WithCache(time.Minute)),
+	}
+	expected := []int64{1, 2, 3, 3}
+	for i, c := range clients {
+		var res Service1Response
+		if err := c.Call(context.Background(), "Multiply", &Service1Request{4, 2}, &res); err != nil || res.Result != 8 {
+			t.Errorf("expected 8, but got %d, %v", res.Result, err)
+		}
+		if n := atomic.LoadInt64(&hits); n != expected[i] {
+			t.Errorf("client %d: expected %d server hits, but got %d", i, expected[i], n)
+		}
+	}
+	if len(store.entries) != 1 {
+		t.Errorf("expected the response in the shared store, but got %d entries", len(store.entries))
+	}
+
+	// A store alone doesn't enable the cache.
+	c := NewClient(s.URL, WithCacheStore(store))
+	var res Service1Response
+	if err := c.Call(context.Background(), "Multiply", &Service1Request{4, 2}, &res); err != nil {
+		t.Error("Expected err to be nil, but got:", err)
+	}
+	if n := atomic.LoadInt64(&hits); n != 4 {
+		t.Errorf("expected a server hit without WithCache, but got %d hits", n)
+	}
+}
+
+func TestMemoryCacheExpiry(t *testing.T) {
+	cache := NewMemoryCache()
+	cache.Set("a", []byte("response"), time.Hour)
+	cache.Set("b", []byte("response"), -time.Second)
+	if response, ok := cache.Get("a"); !ok || string(response) != "response" {
+		t.Errorf("expected cached response, but got %q, %v", response, ok)
+	}
+	if _, ok := cache.Get("b"); ok {
+		t.Error("expected expired response to be missing")
+	}
+	if _, ok := cache.Get("c"); ok {
+		t.Error("expected missing response")
+	}
+}
+
+func TestMemoryCacheSweep(t *testing.T) {
+	cache := NewMemoryCache()
+	for i := 0; i < 1000; i++ {
+		cache.Set(strconv.Itoa(i), []byte("response"), -time.Second)
+	}
+	cache.Set("live", []byte("response"), time.Hour)
+	if n := len(cache.entries); n > minSweep {
+		t.Errorf("expected expired responses to be removed, but got %d entries", n)
+	}
+	if _, ok := cache.Get("live"); !ok {
+		t.Error("expected the live response to be kept")
+	}
+}
+
+func TestClientCacheCallRaw(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf, _ := EncodeServerResponse(&Service1Response{8})
+		w.Write(buf)
+	}))
+	defer s.Close()
+
+	c := NewClient(s.URL, WithCache(time.Minute))
+	for i := 0; i < 3; i++ {
+		var res Service1Response
+		raw, err := c.CallRaw(context.Background(), "Multiply", &Service1Request{4, 2}, &res)
+		if err != nil || res.Result != 8 {
+			t.Fatalf("expected 8, but got %d, %v", res.Result, err)
+		}
+		// Changing the returned response doesn't change the cached one.
+		for j := range raw {
+			raw[j] = ' '
+		}
+	}
+}
+
+func TestClientCacheControl(t *testing.T) {
+	var hits int64
+	status := http.StatusOK
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&hits, 1)
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		buf, _ := EncodeServerResponse(&struct{ Methods []string }{[]string{"a", "b"}})
+		w.Write(buf)
+	}))
+	defer s.Close()
+
+	c := NewClient(s.URL, WithCache(time.Minute))
+	list := func(ctx context.Context) error {
+		var reply struct{ Methods []string }
+		return c.Call(ctx, "system.listMethods", &struct{}{}, &reply)
+	}
+
+	// Non-200 results aren't cached.
+	status = http.StatusServiceUnavailable
+	if err := list(context.Background()); err == nil {
+		t.Error("expected error, but got nil")
+	}
+	status = http.StatusOK
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := list(context.Background()); err != nil {
+				t.Error("Expected err to be nil, but got:", err)
+			}
+		}()
+	}
+	wg.Wait()
+	before := atomic.LoadInt64(&hits)
+	if err := list(context.Background()); err != nil {
+		t.Error("Expected err to be nil, but got:", err)
+	}
+	if n := atomic.LoadInt64(&hits); n != before {
+		t.Errorf("expected cached response, but the server was hit")
+	}
+	cacheHits, misses := c.CacheStats()
+	if cacheHits+misses != 12 || cacheHits < 1 {
+		t.Errorf("expected 12 cached calls, but got %d hits and %d misses", cacheHits, misses)
+	}
+
+	if err := list(NoCache(context.Background())); err != nil {
+		t.Error("Expected err to be nil, but got:", err)
+	}
+	if n := atomic.LoadInt64(&hits); n != before+1 {
+		t.Errorf("expected NoCache to bypass the cache")
+	}
+
+	c.Invalidate("system.listMethods")
+	if err := list(context.Background()); err != nil {
+		t.Error("Expected err to be nil, but got:", err)
+	}
+	if n := atomic.LoadInt64(&hits); n != before+2 {
+		t.Errorf("expected Invalidate to discard the cached response")
+	}
+	if err := list(context.Background()); err != nil {
+		t.Error("Expected err to be nil, but got:", err)
+	}
+	if n := atomic.LoadInt64(&hits); n != before+2 {
+		t.Errorf("expected the new response to be cached")
+	}
+}
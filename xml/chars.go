@@ -0,0 +1,129 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// InvalidCharPolicy tells the encoder what to do with characters XML 1.0
+// doesn't allow, such as most ASCII control characters, in strings.
+type InvalidCharPolicy int
+
+const (
+	// InvalidCharsKeep writes invalid characters as they are, which most
+	// parsers reject. This is the default.
+	InvalidCharsKeep InvalidCharPolicy = iota
+	// InvalidCharsStrip removes invalid characters.
+	InvalidCharsStrip
+	// InvalidCharsEscape replaces invalid characters by their code point,
+	// written as the text \uXXXX.
+	InvalidCharsEscape
+	// InvalidCharsReject makes encoding fail with an *EncodeError naming
+	// the value holding the first invalid character.
+	InvalidCharsReject
+)
+
+// WithInvalidChars sets how the encoder handles characters XML 1.0
+// doesn't allow in strings.
+func WithInvalidChars(policy InvalidCharPolicy) Option {
+	return func(o *options) {
+		o.invalidChars = policy
+	}
+}
+
+// EncodeError is a failure to encode the value at Path, such as
+// "params[0].lines[2]".
+type EncodeError struct {
+	Path string
+	Err  error
+}
+
+func (e *EncodeError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+func (e *EncodeError) Unwrap() error {
+	return e.Err
+}
+
+// encodeError prefixes the path of err, the failure to encode the value
+// at path, with path.
+func encodeError(path string, err error) error {
+	if e, ok := err.(*EncodeError); ok {
+		return &EncodeError{Path: path + e.Path, Err: e.Err}
+	}
+	return &EncodeError{Path: path, Err: err}
+}
+
+// isXMLChar reports whether r is allowed in XML 1.0 documents.
+func isXMLChar(r rune) bool {
+	return r == '\t' || r == '\n' || r == '\r' ||
+		r >= 0x20 && r <= 0xD7FF ||
+		r >= 0xE000 && r <= 0xFFFD ||
+		r >= 0x10000 && r <= 0x10FFFF
+}
+
+// sanitize applies the policy to s. Rejected strings are returned
+// stripped, along with the error.
+func (p InvalidCharPolicy) sanitize(s string) (string, error) {
+	if p == InvalidCharsKeep || strings.IndexFunc(s, func(r rune) bool { return !isXMLChar(r) }) < 0 {
+		return s, nil
+	}
+	var (
+		b   strings.Builder
+		err error
+	)
+	for _, r := range s {
+		switch {
+		case isXMLChar(r):
+			b.WriteRune(r)
+		case p == InvalidCharsEscape:
+			fmt.Fprintf(&b, `\u%04X`, r)
+		case p == InvalidCharsReject && err == nil:
+			err = fmt.Errorf("invalid XML character %U", r)
+		}
+	}
+	return b.String(), err
+}
+
+// invalidCharRef matches numeric character references.
+var invalidCharRef = regexp.MustCompile(`&#(x[0-9a-fA-F]+|[0-9]+);`)
+
+// stripInvalidChars removes the control characters XML 1.0 doesn't allow
+// from a document, and the character references to them. Only ASCII is
+// examined, so that documents in any ASCII-compatible charset are left
+// otherwise intact.
+func stripInvalidChars(data string) string {
+	clean := make([]byte, 0, len(data))
+	for i := 0; i < len(data); i++ {
+		if c := data[i]; c >= 0x20 || isXMLChar(rune(c)) {
+			clean = append(clean, c)
+		}
+	}
+	data = string(clean)
+	if !strings.Contains(data, "&#") {
+		return data
+	}
+	return invalidCharRef.ReplaceAllStringFunc(data, func(ref string) string {
+		num := ref[2 : len(ref)-1]
+		var (
+			n   int64
+			err error
+		)
+		if num[0] == 'x' {
+			n, err = strconv.ParseInt(num[1:], 16, 32)
+		} else {
+			n, err = strconv.ParseInt(num, 10, 32)
+		}
+		if err != nil || !isXMLChar(rune(n)) {
+			return ""
+		}
+		return ref
+	})
+}
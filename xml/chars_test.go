@@ -0,0 +1,85 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+type StructLog struct {
+	Log string
+}
+
+type StructLogLines struct {
+	Entries []StructLog
+}
+
+func TestEncodeInvalidChars(t *testing.T) {
+	req := &StructLogLines{[]StructLog{{"ok"}, {"bell\x07 and \x1b[0m"}}}
+	tests := []struct {
+		policy   InvalidCharPolicy
+		expected string
+	}{
+		{InvalidCharsKeep, "bell\x07 and \x1b[0m"},
+		{InvalidCharsStrip, "bell and [0m"},
+		{InvalidCharsEscape, `bell\u0007 and \u001B[0m`},
+	}
+	for _, test := range tests {
+		xml, err := NewEncoder(WithInvalidChars(test.policy)).EncodeServerResponse(req)
+		if err != nil {
+			t.Errorf("policy %d: %v", test.policy, err)
+		}
+		expected := "<methodResponse><params><param><value><array><data><value><struct><member><name>Log</name><value><string>ok</string></value></member></struct></value><value><struct><member><name>Log</name><value><string>" + test.expected + "</string></value></member></struct></value></data></array></value></param></params></methodResponse>"
+		if string(xml) != expected {
+			t.Errorf("policy %d: expected\n\n%q\n\nbut got:\n\n%q\n", test.policy, expected, xml)
+		}
+	}
+
+	_, err := NewEncoder(WithInvalidChars(InvalidCharsReject)).EncodeServerResponse(req)
+	if e, ok := err.(*EncodeError); !ok || e.Path != "params[0][1].Log" {
+		t.Errorf("expected *EncodeError for params[0][1].Log, but got %v", err)
+	}
+
+	raw := &struct {
+		Raw  string `xmlrpc:",rawxml"`
+		Name string
+		Log  string
+	}{Name: "ok", Log: "bell\x07"}
+	_, err = NewEncoder(WithInvalidChars(InvalidCharsReject)).EncodeServerResponse(raw)
+	if e, ok := err.(*EncodeError); !ok || e.Path != "params[1]" {
+		t.Errorf("expected *EncodeError for params[1] after a rawxml field, but got %v", err)
+	}
+}
+
+func TestDecodeSupervisordLog(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/supervisord_readProcessLog.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := new(StructLog)
+	if err := DecodeClientResponse(bytes.NewReader(data), res); err == nil {
+		t.Error("expected error, but got nil")
+	}
+
+	res = new(StructLog)
+	if err := NewDecoder(Lenient()).DecodeClientResponse(bytes.NewReader(data), res); err != nil {
+		t.Fatal("XML2RPC lenient conversion failed", err)
+	}
+	expected := "2013-05-14 10:12:03,118 INFO spawned: 'worker' with pid 4121\n[32m[ok][0m connected to queue\nprogress: 10%20%30%\npayload: <job id=7>  done\n"
+	if res.Log != expected {
+		t.Errorf("expected:\n\n%q\n\nbut got:\n\n%q\n", expected, res.Log)
+	}
+}
+
+func TestStripInvalidChars(t *testing.T) {
+	data := "<string>a&#1;b&#x1F;c&#65;&#x10FFFF;\x00d\xe9</string>"
+	expected := "<string>abc&#65;&#x10FFFF;d\xe9</string>"
+	if stripped := stripInvalidChars(data); stripped != expected {
+		t.Errorf("expected %q, but got %q", expected, stripped)
+	}
+}
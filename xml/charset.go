@@ -0,0 +1,82 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/rogpeppe/go-charset/charset"
+)
+
+// singleByteCharset maps runes to the bytes encoding them in a charset
+// where every character is a single byte, such as ISO-8859-1.
+type singleByteCharset struct {
+	name  string
+	bytes map[rune]byte
+}
+
+var charsets = struct {
+	sync.Mutex
+	m map[string]*singleByteCharset
+}{m: make(map[string]*singleByteCharset)}
+
+// lookupCharset returns the single-byte charset called name. Its table is
+// built by decoding every byte value with the charset-aware reader, so any
+// single-byte charset the reader knows can be written.
+func lookupCharset(name string) (*singleByteCharset, error) {
+	key := strings.ToLower(name)
+	charsets.Lock()
+	defer charsets.Unlock()
+	if c, ok := charsets.m[key]; ok {
+		return c, nil
+	}
+
+	all := make([]byte, 256)
+	for i := range all {
+		all[i] = byte(i)
+	}
+	r, err := charset.NewReader(name, bytes.NewReader(all))
+	if err != nil {
+		return nil, err
+	}
+	decoded, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if utf8.RuneCount(decoded) != len(all) {
+		return nil, fmt.Errorf("xml: %s is not a single-byte charset", name)
+	}
+
+	c := &singleByteCharset{name: name, bytes: make(map[rune]byte, len(all))}
+	n := 0
+	for _, r := range string(decoded) {
+		if r != utf8.RuneError {
+			c.bytes[r] = byte(n)
+		}
+		n++
+	}
+	charsets.m[key] = c
+	return c, nil
+}
+
+// encode converts the UTF-8 document s to the charset, writing runes the
+// charset lacks as numeric character references.
+func (c *singleByteCharset) encode(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		if b, ok := c.bytes[r]; ok {
+			out = append(out, b)
+		} else {
+			out = append(out, "&#"+strconv.Itoa(int(r))+";"...)
+		}
+	}
+	return out
+}
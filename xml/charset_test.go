@@ -0,0 +1,78 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/gorilla/rpc"
+)
+
+type StructCharset struct {
+	Text string
+}
+
+func TestEncodeLatin1(t *testing.T) {
+	req := &StructCharset{"Café – ☃"}
+	data, err := NewEncoder(WithCharset("ISO-8859-1")).EncodeServerResponse(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?><methodResponse><params><param><value><string>Caf\xe9 &#8211; &#9731;</string></value></param></params></methodResponse>"
+	if string(data) != expected {
+		t.Errorf("wrong document:\n\n%q\n\nexpected:\n\n%q\n", data, expected)
+	}
+	if utf8.Valid(data) {
+		t.Error("expected Latin-1 bytes, but got valid UTF-8")
+	}
+
+	res := new(StructCharset)
+	if err := DecodeClientResponse(bytes.NewReader(data), res); err != nil {
+		t.Error("XML2RPC conversion failed", err)
+	}
+	if !reflect.DeepEqual(res, req) {
+		t.Error("XML2RPC conversion failed")
+		t.Error("Expected", req)
+		t.Error("Got", res)
+	}
+}
+
+func TestEncodeUnknownCharset(t *testing.T) {
+	if _, err := NewEncoder(WithCharset("x-unknown")).EncodeServerResponse(&StructCharset{"a"}); err == nil {
+		t.Error("expected error, but got nil")
+	}
+}
+
+func TestCodecLatin1(t *testing.T) {
+	s := rpc.NewServer()
+	s.RegisterCodec(NewCodec(WithCharset("ISO-8859-1")), "text/xml")
+	s.RegisterService(new(Service1), "")
+
+	buf, _ := EncodeClientRequest("Service1.Multiply", &Service1Request{4, 2})
+	r, _ := http.NewRequest("POST", "http://localhost:8080/", bytes.NewBuffer(buf))
+	r.Header.Set("Content-Type", "text/xml")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+
+	if ct := w.Header().Get("Content-Type"); ct != "text/xml; charset=ISO-8859-1" {
+		t.Errorf("wrong Content-Type: %s", ct)
+	}
+	if !strings.HasPrefix(w.Body.String(), `<?xml version="1.0" encoding="ISO-8859-1"?>`) {
+		t.Errorf("expected XML declaration, but got %s", w.Body)
+	}
+	var res Service1Response
+	if err := DecodeClientResponse(w.Body, &res); err != nil {
+		t.Fatal(err)
+	}
+	if res.Result != 8 {
+		t.Errorf("Wrong response: %v.", res.Result)
+	}
+}
@@ -5,22 +5,195 @@
 package xml
 
 import (
+	"bytes"
+	"context"
+	"fmt"
 	"io"
-	"io/ioutil"
+	"net/http"
+	"time"
 )
 
 // EncodeClientRequest encodes parameters for a XML-RPC client request.
 func EncodeClientRequest(method string, args interface{}) ([]byte, error) {
-	xml, err := rpcRequest2XML(method, args)
-	return []byte(xml), err
+	return NewEncoder(defaults...).EncodeClientRequest(method, args)
 }
 
 // DecodeClientResponse decodes the response body of a client request into
 // the interface reply.
 func DecodeClientResponse(r io.Reader, reply interface{}) error {
-	rawxml, err := ioutil.ReadAll(r)
+	return NewDecoder(defaults...).DecodeClientResponse(r, reply)
+}
+
+// ----------------------------------------------------------------------------
+// Client
+// ----------------------------------------------------------------------------
+
+// Client calls the methods of an XML-RPC server over HTTP.
+type Client struct {
+	url        string
+	httpClient *http.Client
+	encoder    *Encoder
+	decoder    *Decoder
+	reauth     *reauth
+	cache      *clientCache
+	stats      clientStats
+}
+
+// NewClient returns a Client for the server at url. The options apply to
+// the encoding of requests and the decoding of responses.
+func NewClient(url string, opts ...Option) *Client {
+	o := newOptions(opts)
+	c := &Client{
+		url:        url,
+		httpClient: o.httpClient,
+		encoder:    &Encoder{opts: o},
+		decoder:    &Decoder{opts: o},
+	}
+	if c.httpClient == nil {
+		c.httpClient = http.DefaultClient
+	}
+	if o.expectContinue > 0 {
+		c.httpClient = expectContinueClient(c.httpClient, o.expectContinue)
+	}
+	if o.cache {
+		c.cache = &clientCache{store: o.cacheStore, generations: make(map[string]int)}
+		if c.cache.store == nil {
+			c.cache.store = NewMemoryCache()
+		}
+	}
+	if o.reauth != nil {
+		c.reauth = &reauth{reauthConfig: *o.reauth}
+	}
+	return c
+}
+
+// WithHTTPClient makes a Client send its requests with client instead of
+// http.DefaultClient.
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = client
+	}
+}
+
+// ExpectContinue makes a Client send its requests with an
+// "Expect: 100-continue" header, so that a server rejecting a request,
+// such as for its size or a failed authentication, does so before the
+// body is sent. The body is sent anyway if the server hasn't answered
+// within timeout. The timeout is set on the transport of the HTTP client,
+// which must be an *http.Transport.
+func ExpectContinue(timeout time.Duration) Option {
+	return func(o *options) {
+		o.expectContinue = timeout
+	}
+}
+
+// expectContinueClient returns a copy of client, whose transport waits for
+// timeout for the interim response to requests expecting one.
+func expectContinueClient(client *http.Client, timeout time.Duration) *http.Client {
+	rt := client.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	transport, ok := rt.(*http.Transport)
+	if !ok {
+		return client
+	}
+	transport = transport.Clone()
+	transport.ExpectContinueTimeout = timeout
+	c := *client
+	c.Transport = transport
+	return &c
+}
+
+// Call calls method with the parameters held by args, a pointer to a
+// struct with a field per parameter, and decodes the result into reply.
+// Faults are returned as errors, mapped as configured with MapFault.
+func (c *Client) Call(ctx context.Context, method string, args, reply interface{}) error {
+	return c.retry(ctx, func() error {
+		return c.call(ctx, method, args, reply, nil)
+	})
+}
+
+// CallRaw is like Call, returning the response as it was received too,
+// such as to log it. The response is read whole before being decoded.
+func (c *Client) CallRaw(ctx context.Context, method string, args, reply interface{}) ([]byte, error) {
+	var raw []byte
+	err := c.retry(ctx, func() error {
+		return c.call(ctx, method, args, reply, &raw)
+	})
+	return raw, err
+}
+
+// retry runs call, and runs it again after logging in if it fails because
+// the session expired, as configured with WithReauth. The outcome of the
+// last attempt is counted in the stats, and faults are mapped.
+func (c *Client) retry(ctx context.Context, call func() error) error {
+	if c.reauth == nil || inLogin(ctx) {
+		return c.decoder.opts.mapError(c.stats.record(call()))
+	}
+	generation := c.reauth.current()
+	err := call()
+	if fault, ok := err.(Fault); ok && c.reauth.isExpired(&fault) {
+		if err = c.reauth.refresh(ctx, c, generation); err != nil {
+			// The login was counted as a call of its own.
+			c.stats.record(fault)
+			return c.decoder.opts.mapError(err)
+		}
+		err = call()
+	}
+	return c.decoder.opts.mapError(c.stats.record(err))
+}
+
+// call calls method, returning faults as they are. If raw isn't nil, the
+// response is stored in it.
+func (c *Client) call(ctx context.Context, method string, args, reply interface{}, raw *[]byte) error {
+	body, err := c.encoder.EncodeClientRequest(method, args)
+	if err != nil {
+		return &encodingError{err}
+	}
+	if c.cached(ctx, method) {
+		return c.cachedCall(ctx, method, body, reply, raw)
+	}
+	return c.postCall(ctx, body, reply, raw)
+}
+
+// postCall sends the request body, and decodes the response into reply.
+func (c *Client) postCall(ctx context.Context, body []byte, reply interface{}, raw *[]byte) error {
+	resp, err := c.post(ctx, body)
 	if err != nil {
-		return FaultSystemError
+		return err
+	}
+	defer resp.Body.Close()
+	d := c.decoder.withContext(ctx)
+	if raw == nil {
+		return d.decodeAuto(resp.Body, resp.ContentLength, reply)
+	}
+	if *raw, err = d.opts.readLimited(resp.Body); err != nil {
+		return err
+	}
+	return d.decodeResponse(bytes.NewReader(*raw), reply)
+}
+
+// post sends the request body to the server, returning its response if
+// successful, and a *transportError otherwise.
+func (c *Client) post(ctx context.Context, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest("POST", c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", c.encoder.contentType())
+	if c.encoder.opts.expectContinue > 0 {
+		req.Header.Set("Expect", "100-continue")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, &transportError{err}
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, &transportError{fmt.Errorf("xml: %s returned %s", c.url, resp.Status)}
 	}
-	return xml2RPC(string(rawxml), reply)
+	return resp, nil
 }
@@ -0,0 +1,289 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/rpc"
+)
+
+type QuotaError struct {
+	Fault *Fault
+}
+
+func (e *QuotaError) Error() string { return "quota exceeded" }
+func (e *QuotaError) Unwrap() error { return *e.Fault }
+
+type ServiceError struct {
+	Code int
+}
+
+func (e *ServiceError) Error() string { return "service error" }
+
+// faultServer answers every call with a fault whose code is the first
+// parameter of the call.
+func faultServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var args struct{ Code int }
+		if _, err := DecodeServerRequest(r.Body, &args); err != nil {
+			t.Error(err)
+		}
+		w.Write(EncodeFault(Fault{Code: args.Code, String: "Failed"}))
+	}))
+}
+
+func TestClientMapFault(t *testing.T) {
+	s := faultServer(t)
+	defer s.Close()
+
+	c := NewClient(s.URL,
+		MapFault(1001, func(f *Fault) error { return &QuotaError{f} }),
+		MapFaultRange(2000, 2999, func(f *Fault) error { return &ServiceError{f.Code} }),
+		MapFaultFunc(func(code int) bool { return code < 0 }, func(f *Fault) error { return &ServiceError{f.Code} }),
+	)
+	call := func(code int) error {
+		var reply struct{ Result string }
+		return c.Call(context.Background(), "Some.Method", &struct{ Code int }{code}, &reply)
+	}
+
+	err := call(1001)
+	var quota *QuotaError
+	if !errors.As(err, &quota) {
+		t.Errorf("expected *QuotaError, but got %#v", err)
+	}
+	var fault Fault
+	if !errors.As(err, &fault) || fault.Code != 1001 {
+		t.Errorf("expected to find fault 1001 in %#v", err)
+	}
+
+	for _, code := range []int{2500, -1} {
+		err = call(code)
+		var serviceErr *ServiceError
+		if !errors.As(err, &serviceErr) || serviceErr.Code != code {
+			t.Errorf("expected *ServiceError with code %d, but got %#v", code, err)
+		}
+		var fault *Fault
+		if !errors.As(err, &fault) || fault.Code != code {
+			t.Errorf("expected to find fault %d in %#v", code, err)
+		}
+	}
+
+	err = call(1002)
+	if fault, ok := err.(Fault); !ok || fault.Code != 1002 {
+		t.Errorf("expected unmapped fault 1002, but got %#v", err)
+	}
+}
+
+type BugSearch struct {
+	Product string
+	Limit   int
+}
+
+func TestClientImplicitParams(t *testing.T) {
+	var args []interface{}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := DecodeServerRequest(r.Body, &args); err != nil {
+			t.Error(err)
+		}
+		data, _ := EncodeServerResponse(&struct{ Result int }{1})
+		w.Write(data)
+	}))
+	defer s.Close()
+
+	var reply struct{ Result int }
+	c := NewClient(s.URL, WithLeadingParams("db", 2, "secret"))
+	if err := c.Call(context.Background(), "execute_kw", &struct{ Model string }{"res.partner"}, &reply); err != nil {
+		t.Fatal(err)
+	}
+	expected := []interface{}{"db", 2, "secret", "res.partner"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("expected params %v, but got %v", expected, args)
+	}
+
+	c = NewClient(s.URL, WithStructMember("token", "abc"))
+	if err := c.Call(context.Background(), "Bug.search", &struct{ Search BugSearch }{BugSearch{"Go", 10}}, &reply); err != nil {
+		t.Fatal(err)
+	}
+	expected = []interface{}{map[string]interface{}{"Product": "Go", "Limit": 10, "token": "abc"}}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("expected params %v, but got %v", expected, args)
+	}
+
+	if err := c.Call(context.Background(), "Bug.get", &struct{ ID int }{1}, &reply); err == nil {
+		t.Error("expected error for call without struct parameter, but got nil")
+	}
+}
+
+func TestEncodeTransformedParams(t *testing.T) {
+	e := NewEncoder(WithParamTransformer(func(method string, params []Value) ([]Value, error) {
+		return params[1:], nil
+	}))
+	xml, err := e.EncodeClientRequest("Some.Method", &struct {
+		Skipped int
+		Name    string
+		Empty   string
+		Tags    []string
+	}{1, "a < b", "", nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "<methodCall><methodName>Some.Method</methodName><params><param><value><string>a &lt; b</string></value></param><param><value><string></string></value></param><param><value><array><data></data></array></value></param></params></methodCall>"
+	if string(xml) != expected {
+		t.Error("RPC2XML conversion failed")
+		t.Error("Expected", expected)
+		t.Error("Got", string(xml))
+	}
+}
+
+func TestEncodeTransformedMulticall(t *testing.T) {
+	type call struct {
+		MethodName string        `xmlrpc:"methodName"`
+		Params     []interface{} `xmlrpc:"params"`
+	}
+	calls := &struct{ Calls []call }{[]call{
+		{"Bug.search", []interface{}{BugSearch{"Go", 10}}},
+		{"Bug.count", []interface{}{}},
+	}}
+	var methods []string
+	e := NewEncoder(WithLeadingParams("secret"), WithStructMember("token", "abc"), WithParamTransformer(func(method string, params []Value) ([]Value, error) {
+		methods = append(methods, method)
+		return params, nil
+	}))
+	_, err := e.EncodeClientRequest("system.multicall", calls)
+	if err == nil {
+		t.Error("expected error for a call without struct parameter, but got nil")
+	}
+
+	calls.Calls[1].Params = []interface{}{map[string]interface{}{}}
+	methods = nil
+	xml, err := e.EncodeClientRequest("system.multicall", calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct{ Calls []map[string]interface{} }
+	if _, err := DecodeServerRequest(bytes.NewReader(xml), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	expected := []map[string]interface{}{
+		{"methodName": "Bug.search", "params": []interface{}{"secret", map[string]interface{}{"Product": "Go", "Limit": 10, "token": "abc"}}},
+		{"methodName": "Bug.count", "params": []interface{}{"secret", map[string]interface{}{"token": "abc"}}},
+	}
+	if !reflect.DeepEqual(decoded.Calls, expected) {
+		t.Errorf("expected calls %v, but got %v", expected, decoded.Calls)
+	}
+	if !reflect.DeepEqual(methods, []string{"Bug.search", "Bug.count"}) {
+		t.Errorf("expected the methods of the calls, but got %v", methods)
+	}
+}
+
+// countingConn counts the bytes written to the connection.
+type countingConn struct {
+	net.Conn
+	n *int64
+}
+
+func (c *countingConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	atomic.AddInt64(c.n, int64(n))
+	return n, err
+}
+
+// countingClient returns an HTTP client which counts in n the bytes it
+// sends.
+func countingClient(n *int64) *http.Client {
+	var dialer net.Dialer
+	return &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := dialer.DialContext(ctx, network, addr)
+			return &countingConn{conn, n}, err
+		},
+	}}
+}
+
+func TestClientExpectContinue(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Expect") != "100-continue" {
+			t.Errorf("expected Expect: 100-continue, but got %q", r.Header.Get("Expect"))
+		}
+		if r.Header.Get("Authorization") == "" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		io.Copy(ioutil.Discard, r.Body)
+		w.Write(EncodeFault(Fault{Code: 1, String: "Failed"}))
+	}))
+	defer s.Close()
+
+	args := &struct{ Data []byte }{make([]byte, 8<<20)}
+	var reply struct{ Result string }
+
+	var sent int64
+	c := NewClient(s.URL, WithHTTPClient(countingClient(&sent)), ExpectContinue(10*time.Second))
+	err := c.Call(context.Background(), "Upload", args, &reply)
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("expected 401 error, but got %v", err)
+	}
+	if n := atomic.LoadInt64(&sent); n > 64<<10 {
+		t.Errorf("expected the body not to be sent, but %d bytes were", n)
+	}
+}
+
+func TestCodecExpectContinueTooLarge(t *testing.T) {
+	server := rpc.NewServer()
+	server.RegisterCodec(NewCodec(WithMaxBytes(1<<20)), "text/xml")
+	server.RegisterService(new(Service1), "")
+	s := httptest.NewServer(server)
+	defer s.Close()
+
+	args := &struct{ Data []byte }{make([]byte, 8<<20)}
+	var reply Service1Response
+
+	var sent int64
+	c := NewClient(s.URL, WithHTTPClient(countingClient(&sent)), ExpectContinue(10*time.Second))
+	if err := c.Call(context.Background(), "Service1.Multiply", args, &reply); err == nil {
+		t.Error("expected error, but got nil")
+	}
+	if n := atomic.LoadInt64(&sent); n > 64<<10 {
+		t.Errorf("expected the body not to be sent, but %d bytes were", n)
+	}
+}
+
+func TestClientCallRaw(t *testing.T) {
+	var sent []byte
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var args Service1Request
+		if _, err := DecodeServerRequest(r.Body, &args); err != nil {
+			t.Error(err)
+		}
+		sent, _ = EncodeServerResponse(&Service1Response{args.A * args.B})
+		w.Write(sent)
+	}))
+	defer s.Close()
+
+	var res Service1Response
+	raw, err := NewClient(s.URL).CallRaw(context.Background(), "Service1.Multiply", &Service1Request{4, 2}, &res)
+	if err != nil {
+		t.Error("Expected err to be nil, but got:", err)
+	}
+	if res.Result != 8 {
+		t.Errorf("Wrong response: %v.", res.Result)
+	}
+	if !bytes.Equal(raw, sent) {
+		t.Errorf("expected %q, but got %q", sent, raw)
+	}
+}
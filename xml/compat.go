@@ -0,0 +1,61 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"time"
+)
+
+// Compatibility presets bundle the options suiting the XML-RPC
+// implementation of a kind of server. They can be used wherever options
+// are, and extended with more options:
+//
+//	c := xml.NewClient(url, append(xml.CompatPython(), xml.WithHTTPClient(hc))...)
+
+// compatStrictMaxBytes is the document size limit of CompatStrict.
+const compatStrictMaxBytes = 16 << 20
+
+// CompatPython returns the options for Python's xmlrpc modules: untyped
+// strings are trimmed and struct members are named in snake_case. Nil
+// values are written as <nil/>, as by default, which Python reads as None
+// with allow_none set.
+func CompatPython() []Option {
+	return []Option{
+		TrimUntypedStrings(),
+		WithNameMapper(SnakeCase),
+	}
+}
+
+// CompatApacheJava returns the options for Apache XML-RPC servers with
+// extensions enabled: int64 values are written as <ex:i8> and nil as
+// <ex:nil/>.
+func CompatApacheJava() []Option {
+	return []Option{
+		ApacheExtensions(),
+	}
+}
+
+// CompatWordPress returns the options for WordPress and other servers
+// built on the IXR library: dateTime values are GMT and may use the
+// extended ISO 8601 forms, and booleans may be sent as strings.
+func CompatWordPress() []Option {
+	return []Option{
+		WithLocation(time.UTC),
+		Lenient(),
+	}
+}
+
+// CompatStrict returns the options enforcing the specification: documents
+// are validated, values of unknown types and invalid characters are
+// rejected, and nesting and size are limited, to 16 MiB for the latter.
+func CompatStrict() []Option {
+	return []Option{
+		Strict(),
+		ValidateSyntax(),
+		WithMaxDepth(DefaultMaxDepth),
+		WithMaxBytes(compatStrictMaxBytes),
+		WithInvalidChars(InvalidCharsReject),
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// decodeFixture decodes testdata/name into reply with opts.
+func decodeFixture(t *testing.T, name string, reply interface{}, opts ...Option) error {
+	data, err := ioutil.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewDecoder(opts...).DecodeClientResponse(bytes.NewReader(data), reply)
+}
+
+type PythonUser struct {
+	UserID      int
+	DisplayName string
+	LastLogin   time.Time
+	Manager     *PythonUser
+	IsAdmin     bool
+}
+
+func TestCompatPython(t *testing.T) {
+	res := new(struct{ User PythonUser })
+	if err := decodeFixture(t, "compat_python.xml", res, CompatPython()...); err != nil {
+		t.Fatal("XML2RPC conversion failed", err)
+	}
+	expected := PythonUser{7, "Ada Lovelace", time.Date(2024, 3, 15, 10, 22, 1, 0, time.Local), nil, true}
+	if !reflect.DeepEqual(res.User, expected) {
+		t.Error("XML2RPC conversion failed")
+		t.Error("Expected", expected)
+		t.Error("Got", res.User)
+	}
+
+	xml, err := NewEncoder(CompatPython()...).EncodeClientRequest("users.update", &struct{ User PythonUser }{expected})
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "compat_python_request", xml)
+	if !strings.Contains(string(xml), "<name>manager</name><value><nil/></value>") {
+		t.Errorf("expected the nil manager to be written as <nil/>, but got %s", xml)
+	}
+}
+
+type JavaNode struct {
+	ID     int64     `xmlrpc:"id"`
+	Parent *JavaNode `xmlrpc:"parent"`
+	Name   string    `xmlrpc:"name"`
+}
+
+func TestCompatApacheJava(t *testing.T) {
+	res := new(struct{ Node JavaNode })
+	if err := decodeFixture(t, "compat_apache_java.xml", res, CompatApacheJava()...); err != nil {
+		t.Fatal("XML2RPC conversion failed", err)
+	}
+	expected := JavaNode{ID: 9007199254740993, Name: "root"}
+	if !reflect.DeepEqual(res.Node, expected) {
+		t.Error("XML2RPC conversion failed")
+		t.Error("Expected", expected)
+		t.Error("Got", res.Node)
+	}
+
+	xml, err := NewEncoder(CompatApacheJava()...).EncodeClientRequest("Nodes.save", &struct{ Node JavaNode }{expected})
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "compat_apache_java_request", xml)
+}
+
+type WordPressPost struct {
+	ID         string    `xmlrpc:"post_id"`
+	Title      string    `xmlrpc:"post_title"`
+	Date       time.Time `xmlrpc:"post_date_gmt"`
+	Modified   time.Time `xmlrpc:"post_modified_gmt"`
+	Sticky     bool      `xmlrpc:"sticky"`
+	PingStatus bool      `xmlrpc:"ping_status"`
+}
+
+func TestCompatWordPress(t *testing.T) {
+	res := new(struct{ Post WordPressPost })
+	if err := decodeFixture(t, "compat_wordpress.xml", res); err == nil {
+		t.Error("expected error without the preset, but got nil")
+	}
+
+	res = new(struct{ Post WordPressPost })
+	if err := decodeFixture(t, "compat_wordpress.xml", res, CompatWordPress()...); err != nil {
+		t.Fatal("XML2RPC conversion failed", err)
+	}
+	expected := WordPressPost{
+		ID:         "42",
+		Title:      "Hello world!",
+		Date:       time.Date(2024, 3, 15, 10, 22, 1, 0, time.UTC),
+		Modified:   time.Date(2024, 3, 16, 8, 0, 0, 0, time.UTC),
+		PingStatus: true,
+	}
+	if !res.Post.Date.Equal(expected.Date) || !res.Post.Modified.Equal(expected.Modified) {
+		t.Errorf("wrong dates: %v, %v", res.Post.Date, res.Post.Modified)
+	}
+	res.Post.Date, res.Post.Modified = expected.Date, expected.Modified
+	if !reflect.DeepEqual(res.Post, expected) {
+		t.Error("XML2RPC conversion failed")
+		t.Error("Expected", expected)
+		t.Error("Got", res.Post)
+	}
+}
+
+func TestCompatStrict(t *testing.T) {
+	var res struct{ Object string }
+	if err := decodeFixture(t, "compat_strict.xml", &res); err != nil {
+		t.Error("expected unknown type to be skipped without the preset, but got", err)
+	}
+	err := decodeFixture(t, "compat_strict.xml", &res, CompatStrict()...)
+	if err == nil || !strings.Contains(err.Error(), "serializable") {
+		t.Errorf("expected error naming the unknown type, but got %v", err)
+	}
+
+	_, err = NewEncoder(CompatStrict()...).EncodeServerResponse(&struct{ Log string }{"\x1b[0m"})
+	if _, ok := err.(*EncodeError); !ok {
+		t.Errorf("expected *EncodeError, but got %v", err)
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	for name, expected := range map[string]string{
+		"Name":       "name",
+		"UserID":     "user_id",
+		"HTTPServer": "http_server",
+		"Version2":   "version2",
+	} {
+		if s := SnakeCase(name); s != expected {
+			t.Errorf("SnakeCase(%q): expected %q, but got %q", name, expected, s)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// CORSConfig configures the CORS handler.
+type CORSConfig struct {
+	// AllowedOrigins lists the origins allowed to call the endpoint, such
+	// as "https://admin.example.com". "*" allows any origin, and a
+	// wildcard subdomain, such as "https://*.example.com", allows the
+	// subdomains of a domain, but not the domain itself.
+	AllowedOrigins []string
+	// AllowedMethods are sent in answer to preflight requests. They
+	// default to POST and OPTIONS.
+	AllowedMethods []string
+	// AllowedHeaders are sent in answer to preflight requests. They
+	// default to Content-Type.
+	AllowedHeaders []string
+	// MaxAge is how long, in seconds, preflight results may be cached.
+	// Zero leaves it to the browser.
+	MaxAge int
+}
+
+// CORS wraps next, typically an rpc.Server, so that it can be called from
+// browsers on other origins. OPTIONS requests, such as preflights, are
+// answered without being passed to next, and responses to the requests of
+// allowed origins are given the CORS headers:
+//
+//	s := rpc.NewServer()
+//	s.RegisterCodec(xml.NewCodec(), "text/xml")
+//	http.Handle("/RPC2", xml.CORS(s, xml.CORSConfig{
+//		AllowedOrigins: []string{"https://*.example.com"},
+//	}))
+func CORS(next http.Handler, config CORSConfig) http.Handler {
+	if len(config.AllowedMethods) == 0 {
+		config.AllowedMethods = []string{"POST", "OPTIONS"}
+	}
+	if len(config.AllowedHeaders) == 0 {
+		config.AllowedHeaders = []string{"Content-Type"}
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		allowed := origin != "" && config.allows(origin)
+		if allowed {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Add("Vary", "Origin")
+		}
+		if r.Method != "OPTIONS" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Allow", strings.Join(config.AllowedMethods, ", "))
+		if origin != "" && !allowed {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if allowed {
+			w.Header().Set("Access-Control-Allow-Methods", strings.Join(config.AllowedMethods, ", "))
+			w.Header().Set("Access-Control-Allow-Headers", strings.Join(config.AllowedHeaders, ", "))
+			if config.MaxAge > 0 {
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(config.MaxAge))
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// allows reports whether origin matches one of the allowed origins.
+func (c *CORSConfig) allows(origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, allowed := range c.AllowedOrigins {
+		allowed = strings.ToLower(allowed)
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+		i := strings.Index(allowed, "://*.")
+		if i < 0 {
+			continue
+		}
+		// The scheme, and the domain with its leading dot
+		scheme, domain := allowed[:i+3], allowed[i+4:]
+		if strings.HasPrefix(origin, scheme) && strings.HasSuffix(origin, domain) &&
+			len(origin) > len(scheme)+len(domain) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/rpc"
+)
+
+func TestCORSOrigins(t *testing.T) {
+	config := CORSConfig{AllowedOrigins: []string{"https://admin.example.com", "https://*.example.org"}}
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"https://admin.example.com", true},
+		{"https://ADMIN.example.com", true},
+		{"http://admin.example.com", false},
+		{"https://other.example.com", false},
+		{"https://ui.example.org", true},
+		{"https://a.b.example.org", true},
+		{"https://example.org", false},
+		{"https://evilexample.org", false},
+		{"http://ui.example.org", false},
+	}
+	for _, test := range tests {
+		if allowed := config.allows(test.origin); allowed != test.allowed {
+			t.Errorf("%s: expected allowed %v, but got %v", test.origin, test.allowed, allowed)
+		}
+	}
+
+	wildcard := CORSConfig{AllowedOrigins: []string{"*"}}
+	if !wildcard.allows("https://anywhere.test") {
+		t.Error("expected * to allow any origin")
+	}
+}
+
+func TestCORS(t *testing.T) {
+	s := rpc.NewServer()
+	s.RegisterCodec(NewCodec(), "text/xml")
+	s.RegisterService(new(Service1), "")
+	h := CORS(s, CORSConfig{
+		AllowedOrigins: []string{"https://*.example.com"},
+		AllowedHeaders: []string{"Content-Type", "Authorization"},
+		MaxAge:         600,
+	})
+
+	r := httptest.NewRequest("OPTIONS", "/", nil)
+	r.Header.Set("Origin", "https://admin.example.com")
+	r.Header.Set("Access-Control-Request-Method", "POST")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("expected status 204, but got %d", w.Code)
+	}
+	expected := map[string]string{
+		"Access-Control-Allow-Origin":  "https://admin.example.com",
+		"Access-Control-Allow-Methods": "POST, OPTIONS",
+		"Access-Control-Allow-Headers": "Content-Type, Authorization",
+		"Access-Control-Max-Age":       "600",
+	}
+	for name, value := range expected {
+		if got := w.Header().Get(name); got != value {
+			t.Errorf("%s: expected %q, but got %q", name, value, got)
+		}
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("expected empty preflight response, but got %q", w.Body.String())
+	}
+
+	r = httptest.NewRequest("OPTIONS", "/", nil)
+	r.Header.Set("Origin", "https://evil.test")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected status 403, but got %d", w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("expected no Access-Control-Allow-Origin, but got %q", got)
+	}
+
+	buf, _ := EncodeClientRequest("Service1.Multiply", &Service1Request{4, 2})
+	r = httptest.NewRequest("POST", "/", bytes.NewReader(buf))
+	r.Header.Set("Content-Type", "text/xml")
+	r.Header.Set("Origin", "https://admin.example.com")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://admin.example.com" {
+		t.Errorf("expected Access-Control-Allow-Origin https://admin.example.com, but got %q", got)
+	}
+	var res Service1Response
+	if err := DecodeClientResponse(w.Body, &res); err != nil {
+		t.Error("Expected err to be nil, but got:", err)
+	}
+	if res.Result != 8 {
+		t.Errorf("Wrong response: %v.", res.Result)
+	}
+}
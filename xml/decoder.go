@@ -0,0 +1,187 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"context"
+	"io"
+	"strings"
+)
+
+// Decoder converts XML-RPC documents into Go values, according to the
+// options it was created with.
+type Decoder struct {
+	opts  *options
+	state *decodeState
+	ctx   context.Context // passed to ContextUnmarshaler implementations
+}
+
+// decodeState tracks a single decoding, when errors are collected,
+// warnings reported or extra members returned.
+type decodeState struct {
+	path   []string
+	errs   []*DecodeError
+	extras map[string]interface{}
+}
+
+// NewDecoder returns a Decoder configured with opts.
+func NewDecoder(opts ...Option) *Decoder {
+	return &Decoder{opts: newOptions(opts)}
+}
+
+// DecodeClientResponse decodes the response body of a client request into
+// the interface reply.
+func (d *Decoder) DecodeClientResponse(r io.Reader, reply interface{}) error {
+	return d.opts.mapError(d.decodeResponse(r, reply))
+}
+
+// DecodeClientResponseContext is like DecodeClientResponse, passing ctx
+// to the ContextUnmarshaler implementations values are decoded into.
+func (d *Decoder) DecodeClientResponseContext(ctx context.Context, r io.Reader, reply interface{}) error {
+	return d.withContext(ctx).DecodeClientResponse(r, reply)
+}
+
+// withContext returns a copy of d passing ctx to unmarshalers.
+func (d *Decoder) withContext(ctx context.Context) *Decoder {
+	return &Decoder{opts: d.opts, state: d.state, ctx: ctx}
+}
+
+// decodeResponse is like DecodeClientResponse, returning faults as they
+// are.
+func (d *Decoder) decodeResponse(r io.Reader, reply interface{}) error {
+	rawxml, err := d.opts.readLimited(r)
+	if err == ErrTooLarge {
+		return err
+	}
+	if err != nil {
+		return FaultSystemError
+	}
+	if err := setRawXML(reply, rawxml); err != nil {
+		return err
+	}
+	if d.opts.lenient {
+		rawxml = []byte(stripInvalidChars(string(rawxml)))
+	}
+	return d.xml2RPC(string(d.skipGarbage(rawxml)), reply)
+}
+
+// DecodeWithExtras is like DecodeClientResponse, returning the members of
+// structs which match no field of out too, instead of ignoring them. They
+// are keyed by their path, such as "params[0].name", and hold their value
+// as decoded into an interface{}.
+func DecodeWithExtras(r io.Reader, out interface{}) (map[string]interface{}, error) {
+	return NewDecoder(defaults...).DecodeWithExtras(r, out)
+}
+
+// DecodeWithExtras is like the package function of the same name, decoding
+// with the options of d.
+func (d *Decoder) DecodeWithExtras(r io.Reader, out interface{}) (map[string]interface{}, error) {
+	state := &decodeState{extras: make(map[string]interface{})}
+	err := (&Decoder{opts: d.opts, state: state, ctx: d.ctx}).DecodeClientResponse(r, out)
+	if len(state.extras) == 0 {
+		return nil, err
+	}
+	return state.extras, err
+}
+
+// DecodeResponseTree decodes the response body of a client request into
+// the tree of its values, without converting them, such as to inspect
+// responses whose shape isn't known in advance. Faults are returned in the
+// tree rather than as errors.
+func DecodeResponseTree(r io.Reader) (*Response, error) {
+	return NewDecoder(defaults...).DecodeResponseTree(r)
+}
+
+// DecodeResponseTree is like the package function of the same name,
+// decoding with the options of d.
+func (d *Decoder) DecodeResponseTree(r io.Reader) (*Response, error) {
+	rawxml, err := d.opts.readAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return d.parseResponse(string(d.skipGarbage(rawxml)))
+}
+
+// DecodeRawParams decodes the response body of a client request into its
+// parameters, without converting them, so that they can be forwarded as
+// they are. If the response is a fault, it is returned instead.
+func DecodeRawParams(r io.Reader) ([]Param, *Fault, error) {
+	return NewDecoder(defaults...).DecodeRawParams(r)
+}
+
+// DecodeRawParams is like the package function of the same name, decoding
+// with the options of d.
+func (d *Decoder) DecodeRawParams(r io.Reader) ([]Param, *Fault, error) {
+	ret, err := d.DecodeResponseTree(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ret.isFault() {
+		fault := d.getFaultResponse(*ret.Fault)
+		return nil, &fault, nil
+	}
+	return ret.Params, nil, nil
+}
+
+// tracking returns a copy of d to decode a document with, which tracks
+// the path of the current value if errors are collected or warnings
+// reported.
+func (d *Decoder) tracking() *Decoder {
+	if (!d.opts.collect && d.opts.warn == nil) || d.state != nil {
+		return d
+	}
+	return &Decoder{opts: d.opts, state: &decodeState{}, ctx: d.ctx}
+}
+
+// at decodes the value at name, relative to the current path, with decode.
+// When errors are collected, the error decode returns is recorded and nil
+// is returned instead.
+func (d *Decoder) at(name string, decode func() error) error {
+	if d.state == nil {
+		return decode()
+	}
+	d.state.path = append(d.state.path, name)
+	err := decode()
+	if err != nil && d.opts.collect {
+		d.state.errs = append(d.state.errs, &DecodeError{Path: strings.Join(d.state.path, ""), Err: err})
+		err = nil
+	}
+	d.state.path = d.state.path[:len(d.state.path)-1]
+	return err
+}
+
+// warn reports err, which didn't stop the decoding of the current value,
+// to the warning handler.
+func (d *Decoder) warn(err error) {
+	if d.opts.warn == nil {
+		return
+	}
+	path := ""
+	if d.state != nil {
+		path = strings.Join(d.state.path, "")
+	}
+	d.opts.warn(&DecodeError{Path: path, Err: err})
+}
+
+// extra records member, which matches no field, if extra members are
+// returned.
+func (d *Decoder) extra(member Member) {
+	if d.state == nil || d.state.extras == nil {
+		return
+	}
+	value, err := d.value2Interface(member.Value)
+	if err != nil {
+		value = RawValue(member.Value.Raw)
+	}
+	d.state.extras[strings.Join(d.state.path, "")+"."+member.Name] = value
+}
+
+// errors returns the errors collected so far, if any.
+func (d *Decoder) errors() error {
+	if d.state == nil || len(d.state.errs) == 0 {
+		return nil
+	}
+	return &DecodeErrors{Errors: d.state.errs}
+}
@@ -0,0 +1,80 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/rpc"
+)
+
+// DualCodec serves XML-RPC and JSON-RPC clients from the same endpoint.
+// Each request is handled by the XML-RPC Codec or by the JSON-RPC codec,
+// chosen from its Content-Type or, when the Content-Type doesn't name
+// either protocol, from the first non-whitespace byte of its body.
+//
+// As both protocols are served by the same rpc.Server, methods, decode
+// hooks and aliases behave identically, and errors are written back in the
+// protocol of the request. The size limit of the XML-RPC Codec applies to
+// JSON-RPC requests too.
+//
+// A DualCodec should be registered for every Content-Type it serves:
+//
+//	codec := xml.NewDualCodec(xml.NewCodec(), json.NewCodec())
+//	s.RegisterCodec(codec, "text/xml")
+//	s.RegisterCodec(codec, "application/json")
+type DualCodec struct {
+	xml  *Codec
+	json rpc.Codec
+}
+
+// NewDualCodec returns a DualCodec delegating to xml and json.
+func NewDualCodec(xml *Codec, json rpc.Codec) *DualCodec {
+	return &DualCodec{xml: xml, json: json}
+}
+
+// NewRequest returns a CodecRequest of the protocol used by r.
+func (c *DualCodec) NewRequest(r *http.Request) rpc.CodecRequest {
+	if !isJSON(r) {
+		return c.xml.NewRequest(r)
+	}
+	if max := c.xml.decoder.opts.maxBytes; max > 0 {
+		r.Body = http.MaxBytesReader(nil, r.Body, max)
+	}
+	return c.json.NewRequest(r)
+}
+
+// isJSON reports whether r is a JSON-RPC request. When the body has to be
+// inspected, r.Body is replaced by a reader returning the same content.
+func isJSON(r *http.Request) bool {
+	contentType := strings.ToLower(r.Header.Get("Content-Type"))
+	switch {
+	case strings.Contains(contentType, "json"):
+		return true
+	case strings.Contains(contentType, "xml"):
+		return false
+	}
+
+	body := bufio.NewReader(r.Body)
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{body, r.Body}
+	for {
+		b, err := body.ReadByte()
+		if err != nil {
+			return false
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		body.UnreadByte()
+		return b == '{' || b == '['
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/rpc"
+	"github.com/gorilla/rpc/json"
+)
+
+func TestDualCodec(t *testing.T) {
+	s := rpc.NewServer()
+	codec := NewDualCodec(NewCodec(), json.NewCodec())
+	s.RegisterCodec(codec, "text/xml")
+	s.RegisterCodec(codec, "application/json")
+	s.RegisterCodec(codec, "text/plain")
+	s.RegisterService(new(Service1), "")
+
+	var res Service1Response
+	if err := execute(t, s, "Service1.Multiply", &Service1Request{4, 2}, &res); err != nil {
+		t.Error("Expected err to be nil, but got:", err)
+	}
+	if res.Result != 8 {
+		t.Errorf("Wrong response: %v.", res.Result)
+	}
+
+	for _, contentType := range []string{"application/json", "text/plain"} {
+		buf, _ := json.EncodeClientRequest("Service1.Multiply", &Service1Request{3, 5})
+		body := append([]byte("\n  "), buf...)
+		r := httptest.NewRequest("POST", "/", bytes.NewReader(body))
+		r.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+
+		var res Service1Response
+		if err := json.DecodeClientResponse(w.Body, &res); err != nil {
+			t.Errorf("%s: expected err to be nil, but got: %v", contentType, err)
+		}
+		if res.Result != 15 {
+			t.Errorf("%s: wrong response: %v.", contentType, res.Result)
+		}
+	}
+
+	buf, _ := EncodeClientRequest("Service1.Multiply", &Service1Request{6, 7})
+	r := httptest.NewRequest("POST", "/", bytes.NewReader(buf))
+	r.Header.Set("Content-Type", "text/plain")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	if err := DecodeClientResponse(w.Body, &res); err != nil {
+		t.Error("Expected err to be nil, but got:", err)
+	}
+	if res.Result != 42 {
+		t.Errorf("Wrong response: %v.", res.Result)
+	}
+}
+
+func TestDualCodecErrors(t *testing.T) {
+	s := rpc.NewServer()
+	codec := NewDualCodec(NewCodec(WithMaxBytes(256)), json.NewCodec())
+	s.RegisterCodec(codec, "text/xml")
+	s.RegisterCodec(codec, "application/json")
+	s.RegisterService(new(FaultTest), "")
+
+	var res FaultTestResponse
+	err := execute(t, s, "FaultTest.Retry", &FaultTestRequest{4, 2}, &res)
+	if fault, ok := err.(Fault); !ok || fault.Code != 503 {
+		t.Errorf("expected XML-RPC fault, but got %v", err)
+	}
+
+	buf, _ := json.EncodeClientRequest("FaultTest.Retry", &FaultTestRequest{4, 2})
+	r := httptest.NewRequest("POST", "/", bytes.NewReader(buf))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	err = json.DecodeClientResponse(w.Body, &res)
+	if err == nil || err.Error() != "503: Service Unavailable" {
+		t.Errorf("expected JSON-RPC error, but got %v", err)
+	}
+
+	body := `{"method": "FaultTest.Multiply", "params": [{"A": 1, "B": 2}], "id": "` + strings.Repeat("x", 256) + `"}`
+	r = httptest.NewRequest("POST", "/", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected oversized JSON-RPC request to be rejected, but got %d", w.Code)
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"io"
+)
+
+// ExtensionsNamespace is the namespace of the Apache XML-RPC extension
+// types, such as <ex:i8> and <ex:nil>.
+const ExtensionsNamespace = "http://ws.apache.org/xmlrpc/namespaces/extensions"
+
+// Encoder converts Go values into XML-RPC documents, according to the
+// options it was created with.
+type Encoder struct {
+	opts  *options
+	depth int // of the array or struct being encoded
+}
+
+// NewEncoder returns an Encoder configured with opts.
+func NewEncoder(opts ...Option) *Encoder {
+	return &Encoder{opts: newOptions(opts)}
+}
+
+// EncodeClientRequest encodes parameters for a XML-RPC client request.
+//
+// The parameters are rewritten by the transformers set with
+// WithParamTransformer and its helpers.
+func (e *Encoder) EncodeClientRequest(method string, args interface{}) ([]byte, error) {
+	xml, err := e.rpcRequest2XML(method, args)
+	if err != nil {
+		return []byte(xml), err
+	}
+	if len(e.opts.transformers) != 0 {
+		if xml, err = e.transformParams(method, xml); err != nil {
+			return nil, err
+		}
+	}
+	return e.document(xml)
+}
+
+// EncodeServerResponse encodes reply, a pointer to a struct with a field
+// per returned value, as a methodResponse.
+func (e *Encoder) EncodeServerResponse(reply interface{}) ([]byte, error) {
+	xml, err := e.rpcResponse2XML(reply)
+	if err != nil {
+		return []byte(xml), err
+	}
+	return e.document(xml)
+}
+
+// EncodeFault encodes fault as a methodResponse. If the output charset is
+// unknown, the fault is encoded in UTF-8.
+func (e *Encoder) EncodeFault(fault Fault) []byte {
+	xml := e.fault2XML(fault)
+	data, err := e.document(xml)
+	if err != nil {
+		return []byte(xml)
+	}
+	return data
+}
+
+// document converts xml to the output charset, declaring the charset in
+// an XML declaration. UTF-8 documents are returned unchanged.
+func (e *Encoder) document(xml string) ([]byte, error) {
+	if e.opts.charset == "" {
+		return []byte(xml), nil
+	}
+	c, err := lookupCharset(e.opts.charset)
+	if err != nil {
+		return nil, err
+	}
+	decl := `<?xml version="1.0" encoding="` + c.name + `"?>`
+	return append([]byte(decl), c.encode(xml)...), nil
+}
+
+// contentType returns the Content-Type of the documents e writes.
+func (e *Encoder) contentType() string {
+	if e.opts.charset == "" {
+		return "text/xml; charset=utf-8"
+	}
+	return "text/xml; charset=" + e.opts.charset
+}
+
+// EncodeParamsRaw writes params, as decoded by DecodeRawParams, as a
+// methodCall of method, or as a methodResponse if method is empty. Values
+// are written back as they were received, so that they can be forwarded
+// without knowing their schema.
+func EncodeParamsRaw(w io.Writer, method string, params []Param) error {
+	return NewEncoder(defaults...).EncodeParamsRaw(w, method, params)
+}
+
+// EncodeParamsRaw is like the package function of the same name, encoding
+// with the options of e.
+func (e *Encoder) EncodeParamsRaw(w io.Writer, method string, params []Param) error {
+	values := make([]Value, len(params))
+	for i, p := range params {
+		values[i] = p.Value
+	}
+	xml, err := e.params2XML(values)
+	if err != nil {
+		return err
+	}
+	if method == "" {
+		xml = e.root("methodResponse") + xml + "</methodResponse>"
+	} else {
+		xml = e.root("methodCall") + "<methodName>" + escapeString(method) + "</methodName>" +
+			xml + "</methodCall>"
+	}
+	data, err := e.document(xml)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
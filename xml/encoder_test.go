@@ -0,0 +1,121 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files in testdata")
+
+// golden compares got with the content of testdata/name.golden.
+func golden(t *testing.T, name string, got []byte) {
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := ioutil.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(expected) {
+		t.Errorf("%s doesn't match:\n\n%s\n\nbut got:\n\n%s\n", path, expected, got)
+	}
+}
+
+type StructExtensions struct {
+	ID      int64
+	Count   int
+	Comment *string
+}
+
+func TestEncodeApacheExtensions(t *testing.T) {
+	req := &StructExtensions{ID: 9007199254740993, Count: 2}
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{"extensions_off", nil},
+		{"extensions_on", []Option{ApacheExtensions()}},
+	}
+	for _, test := range tests {
+		e := NewEncoder(test.opts...)
+
+		xml, err := e.EncodeClientRequest("Some.Method", req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		golden(t, test.name+"_request", xml)
+
+		xml, err = e.EncodeServerResponse(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		golden(t, test.name+"_response", xml)
+
+		golden(t, test.name+"_fault", e.EncodeFault(NewFault(Fault{Code: 1, String: "Failed"}, map[string]interface{}{"id": int64(3)})))
+	}
+}
+
+func TestDecodeApacheExtensions(t *testing.T) {
+	documents := []string{
+		`<methodResponse xmlns:ex="http://ws.apache.org/xmlrpc/namespaces/extensions"><params><param><value><ex:i8>9007199254740993</ex:i8></value></param><param><value><int>2</int></value></param><param><value><ex:nil/></value></param></params></methodResponse>`,
+		`<methodResponse xmlns:x="http://ws.apache.org/xmlrpc/namespaces/extensions"><params><param><value><x:i8>9007199254740993</x:i8></value></param><param><value><int>2</int></value></param><param><value><x:nil/></value></param></params></methodResponse>`,
+	}
+	expected_res := &StructExtensions{ID: 9007199254740993, Count: 2}
+	for _, document := range documents {
+		res := new(StructExtensions)
+		if err := xml2RPC(document, res); err != nil {
+			t.Error("XML2RPC conversion failed", err)
+		}
+		if !reflect.DeepEqual(res, expected_res) {
+			t.Error("XML2RPC conversion failed")
+			t.Error("Expected", expected_res)
+			t.Error("Got", res)
+		}
+		if err := ValidateResponse(strings.NewReader(document)); err != nil {
+			t.Errorf("expected document to be valid, but got %v", err)
+		}
+	}
+}
+
+func TestEncodeParamsRaw(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/compat_python.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, fault, err := DecodeRawParams(bytes.NewReader(data))
+	if err != nil || fault != nil {
+		t.Fatal("Expected err and fault to be nil, but got:", err, fault)
+	}
+
+	var buf bytes.Buffer
+	if err := EncodeParamsRaw(&buf, "", params); err != nil {
+		t.Fatal(err)
+	}
+	equal, diff, err := Equal(bytes.NewReader(data), &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equal {
+		t.Errorf("re-encoded params differ:\n%s", diff)
+	}
+
+	buf.Reset()
+	if err := EncodeParamsRaw(&buf, "users.update", params); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "<methodCall><methodName>users.update</methodName><params>") {
+		t.Errorf("expected methodCall, but got %s", buf.String())
+	}
+}
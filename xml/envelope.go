@@ -0,0 +1,96 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// WithEnvelope makes the decoder look for the document in the elements of
+// path, outermost first, which some proxies and gateways wrap it in. For a
+// response wrapped in a SOAP envelope:
+//
+//	xml.WithEnvelope("Envelope", "Body")
+//
+// Elements are matched by their local name, without their prefix.
+// Documents which aren't wrapped as described fail to decode.
+func WithEnvelope(path ...string) Option {
+	return func(o *options) {
+		o.envelope = path
+	}
+}
+
+// unwrapEnvelope returns the content of the innermost element of path in
+// xmlraw, along with the XML declaration, if any. Other elements, such as
+// a SOAP header, are skipped. The content is cut from xmlraw as it is, so
+// that it keeps its charset.
+func unwrapEnvelope(xmlraw string, path []string) (string, error) {
+	decoder := xml.NewDecoder(strings.NewReader(xmlraw))
+	decoder.CharsetReader = rawCharsetReader
+	depth, skipped := 0, 0
+	for depth < len(path) {
+		token, err := decoder.RawToken()
+		if err != nil {
+			fault := FaultDecode
+			fault.String += fmt.Sprintf(": no envelope element <%s>", path[depth])
+			return "", fault
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if skipped == 0 && t.Name.Local == path[depth] {
+				depth++
+			} else {
+				skipped++
+			}
+		case xml.EndElement:
+			if skipped == 0 {
+				fault := FaultDecode
+				fault.String += fmt.Sprintf(": no envelope element <%s>", path[depth])
+				return "", fault
+			}
+			skipped--
+		}
+	}
+
+	start := int(decoder.InputOffset())
+	name := path[len(path)-1]
+	end := closingTag(xmlraw, name)
+	if end < start {
+		fault := FaultDecode
+		fault.String += fmt.Sprintf(": envelope element <%s> not closed", name)
+		return "", fault
+	}
+	var decl string
+	if strings.HasPrefix(xmlraw, "<?xml") {
+		if i := strings.Index(xmlraw, "?>"); i >= 0 {
+			decl = xmlraw[:i+2]
+		}
+	}
+	return decl + xmlraw[start:end], nil
+}
+
+// closingTag returns the index of the last end tag of the element called
+// name in xmlraw, whatever its prefix, or -1.
+func closingTag(xmlraw, name string) int {
+	for end := len(xmlraw); ; {
+		i := strings.LastIndex(xmlraw[:end], "</")
+		if i < 0 {
+			return -1
+		}
+		tag := xmlraw[i+2:]
+		if j := strings.IndexByte(tag, '>'); j >= 0 {
+			tag = strings.TrimSpace(tag[:j])
+			if k := strings.IndexByte(tag, ':'); k >= 0 {
+				tag = tag[k+1:]
+			}
+			if tag == name {
+				return i
+			}
+		}
+		end = i
+	}
+}
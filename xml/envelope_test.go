@@ -0,0 +1,44 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"strings"
+	"testing"
+)
+
+const soapResponse = `<?xml version="1.0" encoding="ISO-8859-1"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+  <soap:Header><gw:Trace xmlns:gw="urn:gateway"><gw:Body>ignored</gw:Body></gw:Trace></soap:Header>
+  <soap:Body>
+    <methodResponse><params><param><value><string>caf` + "\xe9" + `</string></value></param><param><value><int>2</int></value></param></params></methodResponse>
+  </soap:Body>
+</soap:Envelope>`
+
+func TestWithEnvelope(t *testing.T) {
+	var reply struct {
+		Name  string
+		Count int
+	}
+	d := NewDecoder(WithEnvelope("Envelope", "Body"))
+	if err := d.DecodeClientResponse(strings.NewReader(soapResponse), &reply); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if reply.Name != "café" || reply.Count != 2 {
+		t.Errorf("expected café and 2, but got %q and %d", reply.Name, reply.Count)
+	}
+
+	fault := "<Envelope><Body><methodResponse><fault><value><struct><member><name>faultCode</name><value><int>4</int></value></member><member><name>faultString</name><value><string>Too many</string></value></member></struct></value></fault></methodResponse></Body></Envelope>"
+	if err := d.DecodeClientResponse(strings.NewReader(fault), &reply); err == nil || err.(Fault).Code != 4 {
+		t.Errorf("expected fault 4, but got %v", err)
+	}
+
+	for _, path := range [][]string{{"Envelope", "Payload"}, {"Wrapper"}} {
+		err := NewDecoder(WithEnvelope(path...)).DecodeClientResponse(strings.NewReader(soapResponse), &reply)
+		if f, ok := err.(Fault); !ok || f.Code != FaultDecode.Code {
+			t.Errorf("%v: expected a decoding fault, but got %v", path, err)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rogpeppe/go-charset/charset"
+)
+
+// document is a methodCall or methodResponse.
+type document struct {
+	XMLName    xml.Name
+	MethodName string  `xml:"methodName"`
+	Params     []Param `xml:"params>param"`
+	Fault      *Value  `xml:"fault>value"`
+}
+
+func parseDocument(r io.Reader) (*document, error) {
+	var doc document
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = charset.NewReader
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// Equal reports whether the XML-RPC documents read from a and b are
+// semantically equal: whitespace around scalars, the int and i4 synonyms,
+// the encoding of numbers, booleans and base64 data, and the order of
+// struct members are ignored. Untyped values are strings. When the
+// documents differ, the returned string describes the differences, one
+// per line.
+func Equal(a, b io.Reader) (bool, string, error) {
+	docA, err := parseDocument(a)
+	if err != nil {
+		return false, "", err
+	}
+	docB, err := parseDocument(b)
+	if err != nil {
+		return false, "", err
+	}
+
+	var diffs []string
+	diff := func(path, format string, args ...interface{}) {
+		diffs = append(diffs, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	if docA.XMLName.Local != docB.XMLName.Local {
+		diff("root", "<%s> != <%s>", docA.XMLName.Local, docB.XMLName.Local)
+	}
+	if docA.MethodName != docB.MethodName {
+		diff("methodName", "%q != %q", docA.MethodName, docB.MethodName)
+	}
+	switch {
+	case docA.Fault != nil && docB.Fault != nil:
+		compareValues("fault", *docA.Fault, *docB.Fault, diff)
+	case docA.Fault != nil || docB.Fault != nil:
+		diff("fault", "only one document is a fault")
+	}
+	if len(docA.Params) != len(docB.Params) {
+		diff("params", "%d params != %d params", len(docA.Params), len(docB.Params))
+	} else {
+		for i := range docA.Params {
+			compareValues(fmt.Sprintf("params[%d]", i), docA.Params[i].Value, docB.Params[i].Value, diff)
+		}
+	}
+
+	return len(diffs) == 0, strings.Join(diffs, "\n"), nil
+}
+
+func compareValues(path string, a, b Value, diff func(path, format string, args ...interface{})) {
+	kindA, textA := normalizeValue(a)
+	kindB, textB := normalizeValue(b)
+	if kindA != kindB {
+		diff(path, "%s != %s", kindA, kindB)
+		return
+	}
+
+	switch kindA {
+	case "struct":
+		membersA, membersB := structMembers(a), structMembers(b)
+		names := make([]string, 0, len(membersA))
+		for name := range membersA {
+			names = append(names, name)
+		}
+		for name := range membersB {
+			if _, ok := membersA[name]; !ok {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			va, okA := membersA[name]
+			vb, okB := membersB[name]
+			switch {
+			case !okA:
+				diff(path+"."+name, "missing in first document")
+			case !okB:
+				diff(path+"."+name, "missing in second document")
+			default:
+				compareValues(path+"."+name, va, vb, diff)
+			}
+		}
+	case "array":
+		if len(a.Array) != len(b.Array) {
+			diff(path, "%d items != %d items", len(a.Array), len(b.Array))
+			return
+		}
+		for i := range a.Array {
+			compareValues(fmt.Sprintf("%s[%d]", path, i), a.Array[i], b.Array[i], diff)
+		}
+	default:
+		if textA != textB {
+			diff(path, "%s %q != %s %q", kindA, textA, kindB, textB)
+		}
+	}
+}
+
+func structMembers(v Value) map[string]Value {
+	members := make(map[string]Value, len(v.Struct))
+	for _, member := range v.Struct {
+		members[member.Name] = member.Value
+	}
+	return members
+}
+
+// normalizeValue returns the type of v and, for scalars, its value in a
+// canonical text form.
+func normalizeValue(v Value) (string, string) {
+	switch {
+	case v.Int != "":
+		return "int", normalizeInt(v.Int)
+	case v.Int4 != "":
+		return "int", normalizeInt(v.Int4)
+	case v.Int8 != "":
+		return "i8", normalizeInt(v.Int8)
+	case v.Double != "":
+		text := strings.TrimSpace(v.Double)
+		if f, err := strconv.ParseFloat(text, 64); err == nil {
+			text = strconv.FormatFloat(f, 'g', -1, 64)
+		}
+		return "double", text
+	case v.String != "":
+		return "string", v.String
+	case v.Boolean != "":
+		return "boolean", strconv.FormatBool(xml2Bool(strings.TrimSpace(v.Boolean)))
+	case v.DateTime != "":
+		return "dateTime.iso8601", strings.TrimSpace(v.DateTime)
+	case v.Base64 != "":
+		text := strings.Join(strings.Fields(v.Base64), "")
+		if data, err := xml2Base64(text); err == nil {
+			text = string(data)
+		}
+		return "base64", text
+	case len(v.Struct) != 0:
+		return "struct", ""
+	case len(v.Array) != 0:
+		return "array", ""
+	}
+
+	if text, ok := rawText(v.Raw); ok {
+		return "string", text
+	}
+	switch kind := rawType(v.Raw); kind {
+	case "i4":
+		return "int", ""
+	case "boolean":
+		return kind, "false"
+	default:
+		return kind, ""
+	}
+}
+
+func normalizeInt(text string) string {
+	text = strings.TrimSpace(text)
+	if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return strconv.FormatInt(n, 10)
+	}
+	return text
+}
@@ -0,0 +1,72 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEqual(t *testing.T) {
+	a := `<methodResponse><params><param><value><struct><member><name>id</name><value><i4>42</i4></value></member><member><name>name</name><value>Johnny</value></member><member><name>ok</name><value><boolean>1</boolean></value></member></struct></value></param><param><value><array><data><value><double>1.50</double></value><value><base64>eW91IGNhbid0IHJlYWQgdGhpcyE=</base64></value></data></array></value></param></params></methodResponse>`
+	b := `<?xml version="1.0"?>
+<methodResponse>
+  <params>
+    <param>
+      <value>
+        <struct>
+          <member><name>ok</name><value><boolean> true </boolean></value></member>
+          <member><name>name</name><value><string>Johnny</string></value></member>
+          <member><name>id</name><value><int> 42 </int></value></member>
+        </struct>
+      </value>
+    </param>
+    <param>
+      <value><array><data>
+        <value><double>1.5</double></value>
+        <value><base64>
+          eW91IGNhbid0
+          IHJlYWQgdGhpcyE=
+        </base64></value>
+      </data></array></value>
+    </param>
+  </params>
+</methodResponse>`
+
+	equal, diff, err := Equal(strings.NewReader(a), strings.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equal {
+		t.Errorf("expected documents to be equal, but got:\n%s", diff)
+	}
+}
+
+func TestEqualDiff(t *testing.T) {
+	a := `<methodResponse><params><param><value><struct><member><name>id</name><value><int>42</int></value></member><member><name>tags</name><value><array><data><value>a</value><value>b</value></data></array></value></member><member><name>old</name><value><nil/></value></member></struct></value></param></params></methodResponse>`
+	b := `<methodResponse><params><param><value><struct><member><name>id</name><value><string>42</string></value></member><member><name>tags</name><value><array><data><value>a</value><value>c</value></data></array></value></member><member><name>new</name><value><nil/></value></member></struct></value></param></params></methodResponse>`
+
+	equal, diff, err := Equal(strings.NewReader(a), strings.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if equal {
+		t.Fatal("expected documents to differ")
+	}
+	expected := `params[0].id: int != string
+params[0].new: missing in first document
+params[0].old: missing in second document
+params[0].tags[1]: string "b" != string "c"`
+	if diff != expected {
+		t.Errorf("wrong diff:\n%s\nexpected:\n%s", diff, expected)
+	}
+}
+
+func TestEqualInvalid(t *testing.T) {
+	_, _, err := Equal(strings.NewReader("<methodResponse>"), strings.NewReader("<methodResponse/>"))
+	if err == nil {
+		t.Error("expected an error for a malformed document")
+	}
+}
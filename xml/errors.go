@@ -0,0 +1,134 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// contextBytes is how many bytes around the error position are kept
+// in SyntaxError.Context, on each side.
+const contextBytes = 24
+
+// SyntaxError represents a document that is not well-formed XML-RPC.
+//
+// Offset is the byte offset of the error in the document, and Context is
+// a short excerpt of the document around it, with non-printable
+// characters replaced by dots.
+type SyntaxError struct {
+	Msg     string
+	Line    int
+	Column  int
+	Offset  int64
+	Context string
+}
+
+func (e *SyntaxError) Error() string {
+	msg := fmt.Sprintf("XML-RPC syntax error on line %d, column %d: %s", e.Line, e.Column, e.Msg)
+	if e.Context != "" {
+		msg += fmt.Sprintf(" (near %q)", e.Context)
+	}
+	return msg
+}
+
+// SyntaxErrors lists all the violations found in a document.
+type SyntaxErrors []*SyntaxError
+
+func (e SyntaxErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// DecodeError is a failure to decode the value at Path, such as
+// "params[0].items[2]", into its destination.
+type DecodeError struct {
+	Path string
+	Err  error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeErrors lists all the values which couldn't be decoded when errors
+// are collected with the CollectErrors option.
+type DecodeErrors struct {
+	Errors []*DecodeError
+}
+
+func (e *DecodeErrors) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func newSyntaxError(data, msg string, line, column int, offset int64) *SyntaxError {
+	return &SyntaxError{
+		Msg:     msg,
+		Line:    line,
+		Column:  column,
+		Offset:  offset,
+		Context: excerpt(data, int(offset)),
+	}
+}
+
+// syntaxError converts xml.SyntaxError returned by decoder into a
+// *SyntaxError positioned in data. Other errors are returned unchanged.
+func syntaxError(decoder *xml.Decoder, data string, err error) error {
+	serr, ok := err.(*xml.SyntaxError)
+	if !ok {
+		return err
+	}
+	_, column := decoder.InputPos()
+	return newSyntaxError(data, serr.Msg, serr.Line, column, decoder.InputOffset())
+}
+
+// excerpt returns the sanitized text of data around offset.
+func excerpt(data string, offset int) string {
+	if offset > len(data) {
+		offset = len(data)
+	}
+	start, end := offset-contextBytes, offset+contextBytes
+	if start < 0 {
+		start = 0
+	}
+	if end > len(data) {
+		end = len(data)
+	}
+	for start > 0 && !utf8.RuneStart(data[start]) {
+		start++
+	}
+	for end < len(data) && !utf8.RuneStart(data[end]) {
+		end--
+	}
+
+	var context strings.Builder
+	if start > 0 {
+		context.WriteString("...")
+	}
+	for _, r := range data[start:end] {
+		if r == utf8.RuneError || !unicode.IsPrint(r) {
+			r = '.'
+		}
+		context.WriteRune(r)
+	}
+	if end < len(data) {
+		context.WriteString("...")
+	}
+	return context.String()
+}
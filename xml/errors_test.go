@@ -0,0 +1,148 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSyntaxErrorPosition(t *testing.T) {
+	base64 := strings.Repeat("QUJD", 50000)
+	data := "<methodResponse><params><param><value><base64>" + base64 + "</base64></value></param><param><value><int>1</value></param></params></methodResponse>"
+
+	var res struct {
+		Data []byte
+		Int  int
+	}
+	err := DecodeClientResponse(strings.NewReader(data), &res)
+	serr, ok := err.(*SyntaxError)
+	if !ok {
+		t.Fatalf("expected *SyntaxError, but got %v", err)
+	}
+	offset := strings.Index(data, "</value></param></params>") + len("</value>")
+	if serr.Line != 1 || serr.Column != offset+1 || serr.Offset != int64(offset) {
+		t.Errorf("wrong position: line %d, column %d, offset %d", serr.Line, serr.Column, serr.Offset)
+	}
+	if serr.Msg != "element <int> closed by </value>" {
+		t.Errorf("wrong message: %s", serr.Msg)
+	}
+	if serr.Context != "...am><value><int>1</value></param></params></metho..." {
+		t.Errorf("wrong context: %q", serr.Context)
+	}
+	if len(err.Error()) > 200 {
+		t.Errorf("error message is too long: %d bytes", len(err.Error()))
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	tests := []struct {
+		data   string
+		offset int
+		want   string
+	}{
+		{"<a>b</a>", 3, "<a>b</a>"},
+		{"<a>\x00\n\tb</a>", 3, "<a>...b</a>"},
+		{strings.Repeat("x", 30) + "<y>" + strings.Repeat("z", 30), 30,
+			"..." + strings.Repeat("x", 24) + "<y>" + strings.Repeat("z", 21) + "..."},
+		{strings.Repeat("é", 30), 31, "..." + strings.Repeat("é", 23) + "..."},
+		{"<a>", 10, "<a>"},
+	}
+	for _, tt := range tests {
+		if got := excerpt(tt.data, tt.offset); got != tt.want {
+			t.Errorf("excerpt(%q, %d) = %q, want %q", tt.data, tt.offset, got, tt.want)
+		}
+	}
+}
+
+func TestCodecSyntaxError(t *testing.T) {
+	codec := NewCodec()
+	body := "<methodCall>\n<methodName>Service1.Multiply</methodName>\n<params></param></methodCall>"
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+
+	_, err := codec.NewRequest(r).Method()
+	serr, ok := err.(*SyntaxError)
+	if !ok {
+		t.Fatalf("expected *SyntaxError, but got %v", err)
+	}
+	if serr.Line != 3 || serr.Column != 17 || serr.Context != "...odName>.<params></param></methodCall>" {
+		t.Errorf("wrong error: %#v", serr)
+	}
+}
+
+type StructCollectXml2Rpc struct {
+	Count int
+	Item  struct {
+		Price float64
+		Tags  []string
+	}
+	Name string
+}
+
+func TestCollectErrors(t *testing.T) {
+	data := "<methodResponse><params><param><value><string>three</string></value></param><param><value><struct><member><name>Price</name><value><double>1.5.0</double></value></member><member><name>Tags</name><value><array><data><value><string>a</string></value><value><int>2</int></value></data></array></value></member></struct></value></param><param><value><string>ok</string></value></param></params></methodResponse>"
+
+	res := new(StructCollectXml2Rpc)
+	if err := xml2RPC(data, res); err == nil {
+		t.Error("expected error, but got nil")
+	}
+
+	res = new(StructCollectXml2Rpc)
+	err := NewDecoder(CollectErrors()).xml2RPC(data, res)
+	errs, ok := err.(*DecodeErrors)
+	if !ok {
+		t.Fatalf("expected *DecodeErrors, but got %v", err)
+	}
+	expected := []string{"params[0]", "params[1].Price", "params[1].Tags[1]"}
+	if len(errs.Errors) != len(expected) {
+		t.Fatalf("expected %d errors, but got %d: %v", len(expected), len(errs.Errors), errs)
+	}
+	for i, path := range expected {
+		if errs.Errors[i].Path != path {
+			t.Errorf("error %d: expected path %s, but got %v", i, path, errs.Errors[i])
+		}
+	}
+	if res.Name != "ok" || res.Item.Tags[0] != "a" {
+		t.Errorf("expected valid values to be decoded, but got %+v", res)
+	}
+}
+
+func TestMalformedNumbers(t *testing.T) {
+	data := "<methodResponse><params><param><value><struct><member><name>N</name><value><int>abc</int></value></member><member><name>F</name><value><double>1.5.0</double></value></member></struct></value></param></params></methodResponse>"
+	type item struct {
+		N int
+		F float64
+	}
+
+	// Malformed numbers are zero, whether warnings are reported or not.
+	var warnings []error
+	decoders := []*Decoder{
+		NewDecoder(),
+		NewDecoder(WithWarningHandler(func(err error) { warnings = append(warnings, err) })),
+	}
+	for i, d := range decoders {
+		res := &struct{ Item item }{item{1, 1}}
+		if err := d.xml2RPC(data, res); err != nil {
+			t.Errorf("decoder %d: Expected err to be nil, but got: %v", i, err)
+		}
+		if res.Item != (item{}) {
+			t.Errorf("decoder %d: expected zero values, but got %+v", i, res.Item)
+		}
+	}
+	if _, err := NewDecoder().DecodeWithExtras(strings.NewReader(data), new(struct{ Item item })); err != nil {
+		t.Error("Expected err to be nil with extras, but got:", err)
+	}
+
+	err := NewDecoder(CollectErrors()).xml2RPC(data, new(struct{ Item item }))
+	errs, ok := err.(*DecodeErrors)
+	if !ok || len(errs.Errors) != 2 {
+		t.Fatalf("expected 2 errors, but got %v", err)
+	}
+	if fault, ok := errs.Errors[0].Err.(Fault); !ok || fault.Code != FaultInvalidParams.Code || errs.Errors[0].Path != "params[0].N" {
+		t.Errorf("expected FaultInvalidParams at params[0].N, but got %v", errs.Errors[0])
+	}
+}
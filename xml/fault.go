@@ -6,6 +6,7 @@ package xml
 
 import (
 	"fmt"
+	"sort"
 )
 
 // Default Faults
@@ -14,6 +15,7 @@ import (
 var (
 	FaultInvalidParams        = Fault{Code: -32602, String: "Invalid Method Parameters"}
 	FaultWrongArgumentsNumber = Fault{Code: -32602, String: "Wrong Arguments Number"}
+	FaultMethodNotFound       = Fault{Code: -32601, String: "Requested Method Not Found"}
 	FaultInternalError        = Fault{Code: -32603, String: "Internal Server Error"}
 	FaultApplicationError     = Fault{Code: -32500, String: "Application Error"}
 	FaultSystemError          = Fault{Code: -32400, String: "System Error"}
@@ -21,9 +23,22 @@ var (
 )
 
 // Fault represents XML-RPC Fault.
+//
+// Faults can be compared with ==, such as err == FaultDecode. Faults with
+// a detail, as returned by NewFault or decoded, are only equal to their
+// copies.
 type Fault struct {
 	Code   int    `xml:"faultCode"`
 	String string `xml:"faultString"`
+
+	// detail is held by a pointer, so that faults remain comparable.
+	detail *faultDetail
+}
+
+// faultDetail holds the members of a fault struct besides faultCode and
+// faultString.
+type faultDetail struct {
+	members map[string]interface{}
 }
 
 // Error satisifies error interface for Fault.
@@ -31,22 +46,80 @@ func (f Fault) Error() string {
 	return fmt.Sprintf("%d: %s", f.Code, f.String)
 }
 
+// Detail returns the members of the fault struct besides faultCode and
+// faultString, or nil if there are none. The map is shared by the copies
+// of f, and must not be modified; use NewFault to add members.
+func (f Fault) Detail() map[string]interface{} {
+	if f.detail == nil {
+		return nil
+	}
+	return f.detail.members
+}
+
+// withDetail returns f with detail as its members besides faultCode and
+// faultString.
+func (f Fault) withDetail(detail map[string]interface{}) Fault {
+	f.detail = nil
+	if len(detail) != 0 {
+		f.detail = &faultDetail{detail}
+	}
+	return f
+}
+
+// NewFault returns the fault answering err, as for the errors service
+// methods return, with the members of detail added to its Detail. Faults
+// keep their code and string, *SyntaxError values become FaultDecode, and
+// other errors FaultApplicationError, with err appended to the string.
+//
+// Service methods may return the result to convey structured context
+// with an error, which clients find in the Detail of the fault:
+//
+//	return xml.NewFault(err, map[string]interface{}{"retryAfter": 30})
+func NewFault(err error, detail map[string]interface{}) Fault {
+	fault := errorFault(err)
+	if len(detail) == 0 {
+		return fault
+	}
+	merged := make(map[string]interface{}, len(fault.Detail())+len(detail))
+	for name, value := range fault.Detail() {
+		merged[name] = value
+	}
+	for name, value := range detail {
+		merged[name] = value
+	}
+	return fault.withDetail(merged)
+}
+
 // Fault2XML is a quick 'marshalling' replacemnt for the Fault case.
+//
+// Detail members are written after faultCode and faultString, sorted by
+// name.
 func fault2XML(fault Fault) string {
-	buffer := "<methodResponse><fault>"
-	xml, _ := rpc2XML(fault)
-	buffer += xml
-	buffer += "</fault></methodResponse>"
-	return buffer
+	return NewEncoder().fault2XML(fault)
 }
 
-type faultValue struct {
-	Value value `xml:"value"`
+func (e *Encoder) fault2XML(fault Fault) string {
+	buffer := e.root("methodResponse")
+	buffer += "<fault><value><struct>"
+	code, _ := e.rpc2XML(fault.Code)
+	buffer += "<member><name>faultCode</name>" + code + "</member>"
+	str, _ := e.rpc2XML(fault.String)
+	buffer += "<member><name>faultString</name>" + str + "</member>"
+	detail := fault.Detail()
+	names := make([]string, 0, len(detail))
+	for name := range detail {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		xml, _ := e.rpc2XML(detail[name])
+		buffer += "<member><name>" + escapeString(name) + "</name>" + xml + "</member>"
+	}
+	buffer += "</struct></value></fault></methodResponse>"
+	return buffer
 }
 
-// IsEmpty returns true if faultValue contain fault.
-//
-// faultValue should be a struct with 2 members.
-func (f faultValue) IsEmpty() bool {
-	return len(f.Value.Struct) == 0
+// faultValue is the <fault> element of a response.
+type faultValue struct {
+	Value Value `xml:"value"`
 }
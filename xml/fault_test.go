@@ -5,7 +5,10 @@
 package xml
 
 import (
+	"bytes"
+	"errors"
 	"net/http"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -42,6 +45,65 @@ func (t *FaultTest) Multiply(r *http.Request, req *FaultTestRequest, res *FaultT
 	return nil
 }
 
+func (t *FaultTest) Retry(r *http.Request, req *FaultTestRequest, res *FaultTestResponse) error {
+	return NewFault(Fault{Code: 503, String: "Service Unavailable"}, map[string]interface{}{
+		"retryAfter": 30,
+		"errorClass": "transient",
+		"servers":    []interface{}{"a", "b"},
+	})
+}
+
+func TestFault2XML(t *testing.T) {
+	fault := NewFault(Fault{Code: 503, String: "Service Unavailable"},
+		map[string]interface{}{"retryAfter": 30, "errorClass": "transient"})
+	expected := "<methodResponse><fault><value><struct><member><name>faultCode</name><value><int>503</int></value></member><member><name>faultString</name><value><string>Service Unavailable</string></value></member><member><name>errorClass</name><value><string>transient</string></value></member><member><name>retryAfter</name><value><int>30</int></value></member></struct></value></fault></methodResponse>"
+	if xml := fault2XML(fault); xml != expected {
+		t.Error("Fault2XML conversion failed")
+		t.Error("Expected", expected)
+		t.Error("Got", xml)
+	}
+}
+
+func TestFaultDetail(t *testing.T) {
+	s := rpc.NewServer()
+	s.RegisterCodec(NewCodec(), "text/xml")
+	s.RegisterService(new(FaultTest), "")
+
+	var res FaultTestResponse
+	err := execute(t, s, "FaultTest.Retry", &FaultTestRequest{4, 2}, &res)
+	fault, ok := err.(Fault)
+	if !ok {
+		t.Fatal("expected error to be of concrete type Fault, but got", err)
+	}
+	if fault.Code != 503 || fault.String != "Service Unavailable" {
+		t.Errorf("wrong fault: %v", fault)
+	}
+	expected := map[string]interface{}{
+		"retryAfter": 30,
+		"errorClass": "transient",
+		"servers":    []interface{}{"a", "b"},
+	}
+	if !reflect.DeepEqual(fault.Detail(), expected) {
+		t.Errorf("wrong fault detail: %v", fault.Detail())
+	}
+}
+
+func TestFaultComparable(t *testing.T) {
+	var reply struct{ Result string }
+	err := DecodeClientResponse(bytes.NewReader(EncodeFault(FaultDecode)), &reply)
+	if err != FaultDecode {
+		t.Errorf("expected FaultDecode, but got %v", err)
+	}
+	// Comparing faults with a detail doesn't panic.
+	err = DecodeClientResponse(bytes.NewReader(EncodeFault(NewFault(FaultDecode, map[string]interface{}{"line": 3}))), &reply)
+	if err == FaultDecode {
+		t.Error("expected the fault with a detail to differ from FaultDecode")
+	}
+	if err != err.(Fault) {
+		t.Error("expected the fault to equal itself")
+	}
+}
+
 func TestFaults(t *testing.T) {
 	s := rpc.NewServer()
 	s.RegisterCodec(NewCodec(), "text/xml")
@@ -82,3 +144,34 @@ func TestFaults(t *testing.T) {
 		t.Errorf("wrong response: %s", fault.String)
 	}
 }
+
+func TestNewFault(t *testing.T) {
+	tests := []struct {
+		err      error
+		code     int
+		str      string
+		expected map[string]interface{}
+	}{
+		{errors.New("disk full"), -32500, "Application Error: disk full", map[string]interface{}{"free": 0, "volume": "data"}},
+		{NewFault(Fault{Code: 503, String: "Service Unavailable"}, map[string]interface{}{"retryAfter": 30}), 503, "Service Unavailable", map[string]interface{}{"retryAfter": 30, "free": 0, "volume": "data"}},
+	}
+	for _, test := range tests {
+		fault := NewFault(test.err, map[string]interface{}{"free": 0, "volume": "data"})
+		var reply struct{ Result string }
+		err := DecodeClientResponse(bytes.NewReader(EncodeFault(fault)), &reply)
+		decoded, ok := err.(Fault)
+		if !ok {
+			t.Fatal("expected error to be of concrete type Fault, but got", err)
+		}
+		if decoded.Code != test.code || decoded.String != test.str {
+			t.Errorf("expected %d: %s, but got %v", test.code, test.str, decoded)
+		}
+		if !reflect.DeepEqual(decoded.Detail(), test.expected) {
+			t.Errorf("expected detail %v, but got %v", test.expected, decoded.Detail())
+		}
+	}
+
+	if fault := NewFault(FaultSystemError, nil); fault.Detail() != nil || fault != FaultSystemError {
+		t.Errorf("expected FaultSystemError without detail, but got %v", fault.Detail())
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"errors"
+)
+
+// faultMapper converts the faults matched by match into errors.
+type faultMapper struct {
+	match     func(code int) bool
+	construct func(f *Fault) error
+}
+
+// MapFault makes the decoder return the error built by construct instead
+// of faults with the given code. The error wraps the Fault, so that
+// errors.As still finds it. Mappings are tried in the order they are
+// given; faults no mapping matches are returned as is.
+func MapFault(code int, construct func(f *Fault) error) Option {
+	return MapFaultFunc(func(c int) bool { return c == code }, construct)
+}
+
+// MapFaultRange is like MapFault for the faults with codes from min to
+// max, inclusive.
+func MapFaultRange(min, max int, construct func(f *Fault) error) Option {
+	return MapFaultFunc(func(c int) bool { return min <= c && c <= max }, construct)
+}
+
+// MapFaultFunc is like MapFault for the faults with codes match reports
+// true for.
+func MapFaultFunc(match func(code int) bool, construct func(f *Fault) error) Option {
+	return func(o *options) {
+		o.faultMappers = append(o.faultMappers, faultMapper{match, construct})
+	}
+}
+
+// mapFault returns the error fault is mapped to.
+func (o *options) mapFault(fault Fault) error {
+	for _, m := range o.faultMappers {
+		if !m.match(fault.Code) {
+			continue
+		}
+		f := fault
+		err := m.construct(&f)
+		if err == nil {
+			return fault
+		}
+		var target Fault
+		if errors.As(err, &target) {
+			return err
+		}
+		return &mappedFault{err: err, fault: f}
+	}
+	return fault
+}
+
+// mapError returns the error err is mapped to, if it is a fault.
+func (o *options) mapError(err error) error {
+	if fault, ok := err.(Fault); ok {
+		return o.mapFault(fault)
+	}
+	return err
+}
+
+// mappedFault wraps the error a fault was mapped to, which doesn't wrap
+// the fault itself.
+type mappedFault struct {
+	err   error
+	fault Fault
+}
+
+func (e *mappedFault) Error() string {
+	return e.err.Error()
+}
+
+func (e *mappedFault) Unwrap() error {
+	return e.err
+}
+
+// As makes errors.As find the fault as a Fault or a *Fault.
+func (e *mappedFault) As(target interface{}) bool {
+	switch t := target.(type) {
+	case *Fault:
+		*t = e.fault
+		return true
+	case **Fault:
+		f := e.fault
+		*t = &f
+		return true
+	}
+	return false
+}
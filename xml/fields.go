@@ -0,0 +1,491 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Struct fields are mapped to members using the xmlrpc struct tag:
+//
+//	Amount int `xmlrpc:"amount,hook=money"`
+//
+// The first part of the tag is the member name; a name of "-" excludes
+// the field. The remaining comma separated options are either flags or
+// key=value pairs. Fields without an xmlrpc tag use the name from their
+// xml tag, and then the field name.
+//
+// As with encoding/json, the members of an embedded struct without a tag
+// are promoted to its parent, unless the parent has members of the same
+// name.
+//
+// The options understood by the decoder and the encoder are:
+//
+//	hook=name     decode the member with the DecodeHook registered as name
+//	default=text  value of the field when the member is absent
+//	layout=text   time.Time layout of a date sent as a string
+//	omitempty     a zero time.Time is left out, or with layout, an empty
+//	              string is the zero time
+//
+// The options apply to the fields of argument and reply structs too, which
+// hold params rather than members, except that params are never left out.
+//
+// A field of a reply struct, of type string or []byte, tagged
+// `xmlrpc:",rawxml"` holds no param, and is set to the whole response as
+// it was received instead, such as to log it along with the result.
+
+// fieldInfo describes how a struct field maps to a member.
+type fieldInfo struct {
+	name   string
+	index  int
+	opts   map[string]string
+	tagged bool // whether the name comes from a tag
+
+	// embedded holds the fields of an untagged embedded struct, whose
+	// members are promoted to the parent.
+	embedded fieldList
+}
+
+type fieldList []fieldInfo
+
+// parseTag splits a struct tag into the member name and its options.
+func parseTag(tag string) (string, map[string]string) {
+	parts := strings.Split(tag, ",")
+	opts := make(map[string]string, len(parts)-1)
+	for _, part := range parts[1:] {
+		if i := strings.Index(part, "="); i >= 0 {
+			opts[part[:i]] = part[i+1:]
+		} else {
+			opts[part] = ""
+		}
+	}
+	return parts[0], opts
+}
+
+// structFields returns the members t maps to, in field order.
+func structFields(t reflect.Type) fieldList {
+	return typeFields(t, map[reflect.Type]bool{t: true})
+}
+
+// typeFields is structFields, where parents holds the structs t is
+// embedded in, which aren't embedded again.
+func typeFields(t reflect.Type, parents map[reflect.Type]bool) fieldList {
+	fields := fieldList{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		embedded := embeddedStruct(f, parents)
+		if f.PkgPath != "" && (embedded == nil || embedded.Kind() == reflect.Ptr) {
+			// The exported fields of unexported embedded structs are still
+			// promoted, unless the struct would need to be allocated.
+			continue
+		}
+		name, opts := parseTag(f.Tag.Get("xmlrpc"))
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			// Options of the xml tag, such as omitempty, don't apply.
+			name, _ = parseTag(f.Tag.Get("xml"))
+		}
+		tagged := name != ""
+		if !tagged {
+			name = f.Name
+		}
+		info := fieldInfo{
+			name:   name,
+			index:  i,
+			opts:   opts,
+			tagged: tagged,
+		}
+		if embedded != nil && !tagged {
+			st := embedded
+			if st.Kind() == reflect.Ptr {
+				st = st.Elem()
+			}
+			parents[st] = true
+			info.embedded = typeFields(st, parents)
+			delete(parents, st)
+		} else if f.PkgPath != "" {
+			continue
+		}
+		fields = append(fields, info)
+	}
+	return fields
+}
+
+// visit calls fn with each field of t, a struct, which is a member, in
+// field order, and its index in t. The fields of embedded structs are
+// promoted, unless hidden by a member of the same name in an outer struct,
+// as the encoder does.
+func (fields fieldList) visit(t reflect.Type, fn func(index []int, f reflect.StructField, info fieldInfo)) {
+	fields.visitHidden(t, nil, nil, fn)
+}
+
+// visitHidden is visit, for the fields of t embedded at index, where
+// hidden holds the names of the members of the outer structs.
+func (fields fieldList) visitHidden(t reflect.Type, index []int, hidden map[string]bool, fn func(index []int, f reflect.StructField, info fieldInfo)) {
+	names := make(map[string]bool, len(hidden)+len(fields))
+	for name := range hidden {
+		names[name] = true
+	}
+	for _, info := range fields {
+		if info.embedded == nil {
+			names[info.name] = true
+		}
+	}
+	for _, info := range fields {
+		f := t.Field(info.index)
+		i := append(index[:len(index):len(index)], info.index)
+		if info.embedded != nil {
+			st := f.Type
+			if st.Kind() == reflect.Ptr {
+				st = st.Elem()
+			}
+			info.embedded.visitHidden(st, i, names, fn)
+		} else if !hidden[info.name] {
+			fn(i, f, info)
+		}
+	}
+}
+
+// embeddedStruct returns the type of f if it is an embedded struct, or
+// pointer to one, other than time.Time and those in parents.
+func embeddedStruct(f reflect.StructField, parents map[reflect.Type]bool) reflect.Type {
+	if !f.Anonymous {
+		return nil
+	}
+	t := f.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == timeType || parents[t] {
+		return nil
+	}
+	return f.Type
+}
+
+// structFields returns the members t maps to, naming the fields without
+// a tag with the configured name mapper.
+func (o *options) structFields(t reflect.Type) fieldList {
+	fields := structFields(t)
+	if o.nameMapper == nil {
+		return fields
+	}
+	o.mapNames(fields)
+	return fields
+}
+
+// mapNames names the fields without a tag, including embedded ones, with
+// the configured name mapper.
+func (o *options) mapNames(fields fieldList) {
+	for i := range fields {
+		if !fields[i].tagged {
+			fields[i].name = o.nameMapper(fields[i].name)
+		}
+		o.mapNames(fields[i].embedded)
+	}
+}
+
+// SnakeCase converts a Go field name into a snake_case member name, as
+// in UserID to user_id. It is meant for WithNameMapper.
+func SnakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// Start a word at a lower to upper case transition, and before
+			// the last capital of an acronym followed by a lower case.
+			if i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) ||
+				i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1])) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// rawXMLIndex returns the index of the field of t, a reply struct, tagged
+// rawxml, or -1 if there is none.
+func rawXMLIndex(t reflect.Type) int {
+	for i := 0; i < t.NumField(); i++ {
+		_, opts := parseTag(t.Field(i).Tag.Get("xmlrpc"))
+		if _, ok := opts["rawxml"]; ok {
+			return i
+		}
+	}
+	return -1
+}
+
+// numParams returns the number of params v, a reply struct, holds.
+func numParams(v reflect.Value) int {
+	if rawXMLIndex(v.Type()) >= 0 {
+		return v.NumField() - 1
+	}
+	return v.NumField()
+}
+
+// paramField returns the field of v, a reply struct, holding the i-th
+// param, and the options of its tag, such as hook.
+func paramField(v reflect.Value, i int) (reflect.Value, fieldInfo) {
+	if raw := rawXMLIndex(v.Type()); raw >= 0 && i >= raw {
+		i++
+	}
+	f := v.Type().Field(i)
+	name, opts := parseTag(f.Tag.Get("xmlrpc"))
+	return v.Field(i), fieldInfo{name: name, index: i, opts: opts, tagged: name != ""}
+}
+
+// setRawXML sets the field of reply tagged rawxml, if any, to data.
+func setRawXML(reply interface{}, data []byte) error {
+	v := reflect.ValueOf(reply).Elem()
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	i := rawXMLIndex(v.Type())
+	if i < 0 {
+		return nil
+	}
+	field := v.Field(i)
+	switch {
+	case field.Kind() == reflect.String:
+		field.SetString(string(data))
+	case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.Uint8:
+		field.SetBytes(append([]byte(nil), data...))
+	default:
+		fault := FaultInvalidParams
+		fault.String += fmt.Sprintf(": rawxml field %s.%s must be a string or []byte", v.Type(), v.Type().Field(i).Name)
+		return fault
+	}
+	return nil
+}
+
+// lookup finds the field of v, a struct, for the member called name.
+// The fields of embedded structs are returned with the index of the
+// embedded struct, allocating it if it is a nil pointer.
+func (fields fieldList) lookup(v reflect.Value, name string) (reflect.Value, fieldInfo, bool) {
+	for _, info := range fields {
+		if info.embedded == nil && info.name == name {
+			return v.Field(info.index), info, true
+		}
+	}
+	for _, info := range fields {
+		if info.embedded == nil || !info.embedded.has(name) {
+			continue
+		}
+		e := v.Field(info.index)
+		if e.Kind() == reflect.Ptr {
+			if e.IsNil() {
+				e.Set(reflect.New(e.Type().Elem()))
+			}
+			e = e.Elem()
+		}
+		if f, sub, ok := info.embedded.lookup(e, name); ok {
+			sub.index = info.index
+			return f, sub, true
+		}
+	}
+	// Uppercase first letter for field name to deal with
+	// methods in lowercase, which cannot be used. Fields renamed by a tag
+	// are only matched by their tag name.
+	if upper := uppercaseFirst(name); upper != name && fields.has(upper) {
+		if f, info, ok := fields.lookup(v, upper); ok && !info.tagged {
+			return f, info, true
+		}
+	}
+	return reflect.Value{}, fieldInfo{}, false
+}
+
+// has reports whether fields, or those of embedded structs, include a
+// member called name.
+func (fields fieldList) has(name string) bool {
+	for _, info := range fields {
+		if info.embedded == nil && info.name == name || info.embedded.has(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupFold finds the field of v, a struct, for the member called name,
+// ignoring case. Like lookup, it prefers fields of v to those of embedded
+// structs, and shallower embedded fields to deeper ones. It fails if
+// several fields match at the same level.
+func (fields fieldList) lookupFold(v reflect.Value, name string) (reflect.Value, fieldInfo, bool, error) {
+	path, match, ok, err := fields.foldMatch(v.Type(), name)
+	if !ok || err != nil {
+		return reflect.Value{}, fieldInfo{}, false, err
+	}
+	f := fieldByIndex(v, append(path, match.index))
+	if len(path) != 0 {
+		match.index = path[0]
+	}
+	return f, match, true, nil
+}
+
+// foldLevel holds the fields of a struct searched by foldMatch, which is
+// embedded in the searched struct through path.
+type foldLevel struct {
+	path   []int
+	t      reflect.Type
+	fields fieldList
+}
+
+// fieldName returns the name of field i, qualified by the name of its
+// struct if it is embedded.
+func (l foldLevel) fieldName(i int) string {
+	if len(l.path) == 0 {
+		return l.t.Field(i).Name
+	}
+	return l.t.Name() + "." + l.t.Field(i).Name
+}
+
+// foldMatch finds the field of t, a struct, for the member called name,
+// ignoring case, one level of embedded structs at a time. path holds the
+// indexes of the embedded fields leading to the struct of the field.
+func (fields fieldList) foldMatch(t reflect.Type, name string) (path []int, match fieldInfo, ok bool, err error) {
+	levels := []foldLevel{{nil, t, fields}}
+	for len(levels) != 0 {
+		var next []foldLevel
+		var found *foldLevel
+		for l, lv := range levels {
+			for _, info := range lv.fields {
+				if info.embedded != nil {
+					et := lv.t.Field(info.index).Type
+					if et.Kind() == reflect.Ptr {
+						et = et.Elem()
+					}
+					next = append(next, foldLevel{append(lv.path[:len(lv.path):len(lv.path)], info.index), et, info.embedded})
+					continue
+				}
+				if !strings.EqualFold(info.name, name) {
+					continue
+				}
+				if found != nil {
+					fault := FaultInvalidParams
+					fault.String += fmt.Sprintf(": member %q matches fields %s and %s of %s", name,
+						found.fieldName(match.index), lv.fieldName(info.index), t)
+					return nil, fieldInfo{}, false, fault
+				}
+				found, match = &levels[l], info
+			}
+		}
+		if found != nil {
+			return found.path, match, true, nil
+		}
+		levels = next
+	}
+	return nil, fieldInfo{}, false, nil
+}
+
+// setDefaults sets the fields of v, a struct, including those of embedded
+// structs, which have a default and whose member name isn't in decoded.
+func (fields fieldList) setDefaults(v reflect.Value, decoded map[string]bool) error {
+	if !fields.hasDefaults() {
+		return nil
+	}
+	var err error
+	fields.visit(v.Type(), func(index []int, f reflect.StructField, info fieldInfo) {
+		text, ok := info.opts["default"]
+		if !ok || decoded[info.name] || err != nil {
+			return
+		}
+		if e := setText(fieldByIndex(v, index), text); e != nil {
+			err = fmt.Errorf("xml: invalid default for %s.%s: %v", v.Type(), f.Name, e)
+		}
+	})
+	return err
+}
+
+// hasDefaults reports whether fields, or those of embedded structs, have
+// a default.
+func (fields fieldList) hasDefaults() bool {
+	for _, info := range fields {
+		if _, ok := info.opts["default"]; ok || info.embedded.hasDefaults() {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldByIndex returns the field of v at index, allocating the embedded
+// structs which are nil pointers.
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for _, i := range index[:len(index)-1] {
+		v = v.Field(i)
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+	}
+	return v.Field(index[len(index)-1])
+}
+
+// setText sets v to the value text represents.
+func setText(v reflect.Value, text string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(text)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(text, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(text, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(text, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// DecodeHook converts v into field, in place of the default conversion,
+// for struct fields tagged with the name the hook was registered under.
+type DecodeHook func(v Value, field reflect.Value) error
+
+var hooks = struct {
+	sync.RWMutex
+	m map[string]DecodeHook
+}{m: make(map[string]DecodeHook)}
+
+// RegisterHook registers hook under name, so that struct fields tagged
+// with `xmlrpc:"member,hook=name"` are decoded by it.
+func RegisterHook(name string, hook DecodeHook) {
+	hooks.Lock()
+	hooks.m[name] = hook
+	hooks.Unlock()
+}
+
+func lookupHook(name string) (DecodeHook, bool) {
+	hooks.RLock()
+	hook, ok := hooks.m[name]
+	hooks.RUnlock()
+	return hook, ok
+}
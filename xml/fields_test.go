@@ -0,0 +1,349 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTag(t *testing.T) {
+	name, opts := parseTag("amount,hook=money,omitempty")
+	if name != "amount" {
+		t.Errorf("wrong name: %s", name)
+	}
+	expected := map[string]string{"hook": "money", "omitempty": ""}
+	if !reflect.DeepEqual(opts, expected) {
+		t.Errorf("wrong options: %v", opts)
+	}
+}
+
+type StructHookXml2Rpc struct {
+	Amount int    `xmlrpc:"amount,hook=cents"`
+	Label  string `xmlrpc:"label"`
+	Skip   string `xmlrpc:"-"`
+}
+
+type StructHookArgs struct {
+	Payment StructHookXml2Rpc
+}
+
+func TestDecodeHook(t *testing.T) {
+	RegisterHook("cents", func(v Value, field reflect.Value) error {
+		n, err := strconv.Atoi(v.Int)
+		field.SetInt(int64(n) * 100)
+		return err
+	})
+
+	req := new(StructHookArgs)
+	err := xml2RPC("<methodResponse><params><param><value><struct><member><name>amount</name><value><int>42</int></value></member><member><name>label</name><value><string>Tip</string></value></member><member><name>Skip</name><value><string>ignored</string></value></member></struct></value></param></params></methodResponse>", req)
+	if err != nil {
+		t.Error("XML2RPC conversion failed", err)
+	}
+	expected_req := &StructHookArgs{StructHookXml2Rpc{4200, "Tip", ""}}
+	if !reflect.DeepEqual(req, expected_req) {
+		t.Error("XML2RPC conversion failed")
+		t.Error("Expected", expected_req)
+		t.Error("Got", req)
+	}
+
+	xml, err := rpcResponse2XML(expected_req)
+	if err != nil {
+		t.Error("RPC2XML conversion failed", err)
+	}
+	expected := "<methodResponse><params><param><value><struct><member><name>amount</name><value><int>4200</int></value></member><member><name>label</name><value><string>Tip</string></value></member></struct></value></param></params></methodResponse>"
+	if xml != expected {
+		t.Error("RPC2XML conversion failed")
+		t.Error("Expected", expected)
+		t.Error("Got", xml)
+	}
+}
+
+func TestDecodeHookParam(t *testing.T) {
+	RegisterHook("cents", func(v Value, field reflect.Value) error {
+		n, err := strconv.Atoi(v.Int)
+		field.SetInt(int64(n) * 100)
+		return err
+	})
+
+	res := new(struct {
+		Amount int `xmlrpc:"amount,hook=cents"`
+		Label  string
+	})
+	err := xml2RPC("<methodResponse><params><param><value><int>42</int></value></param><param><value><string>Tip</string></value></param></params></methodResponse>", res)
+	if err != nil {
+		t.Error("XML2RPC conversion failed", err)
+	}
+	if res.Amount != 4200 || res.Label != "Tip" {
+		t.Errorf("expected the hook to decode the param, but got %+v", res)
+	}
+
+	err = xml2RPC("<methodResponse><params><param><value><int>42</int></value></param></params></methodResponse>", new(struct {
+		Amount int `xmlrpc:",hook=unknown"`
+	}))
+	if err == nil {
+		t.Error("expected error for an unknown hook, but got nil")
+	}
+}
+
+type StructDottedXml2Rpc struct {
+	ABC   string `xmlrpc:"a.b.c"`
+	Field int    `xml:"ns:field,omitempty"`
+}
+
+type StructDottedArgs struct {
+	Value StructDottedXml2Rpc
+}
+
+func TestDottedMemberNames(t *testing.T) {
+	req := new(StructDottedArgs)
+	err := xml2RPC("<methodResponse><params><param><value><struct><member><name>a.b.c</name><value><string>dotted</string></value></member><member><name>ns:field</name><value><int>7</int></value></member></struct></value></param></params></methodResponse>", req)
+	if err != nil {
+		t.Error("XML2RPC conversion failed", err)
+	}
+	expected_req := &StructDottedArgs{StructDottedXml2Rpc{"dotted", 7}}
+	if !reflect.DeepEqual(req, expected_req) {
+		t.Error("XML2RPC conversion failed")
+		t.Error("Expected", expected_req)
+		t.Error("Got", req)
+	}
+
+	xml, err := rpcResponse2XML(expected_req)
+	if err != nil {
+		t.Error("RPC2XML conversion failed", err)
+	}
+	expected := "<methodResponse><params><param><value><struct><member><name>a.b.c</name><value><string>dotted</string></value></member><member><name>ns:field</name><value><int>7</int></value></member></struct></value></param></params></methodResponse>"
+	if xml != expected {
+		t.Error("RPC2XML conversion failed")
+		t.Error("Expected", expected)
+		t.Error("Got", xml)
+	}
+}
+
+type StructDefaultXml2Rpc struct {
+	Retries int     `xmlrpc:"retries,default=7"`
+	Ratio   float64 `xmlrpc:"ratio,default=0.5"`
+	Mode    string  `xmlrpc:"mode,default=auto"`
+	Enabled bool    `xmlrpc:"enabled,default=true"`
+}
+
+type StructDefaultArgs struct {
+	Config StructDefaultXml2Rpc
+	Empty  StructDefaultXml2Rpc
+}
+
+func TestDecodeDefault(t *testing.T) {
+	req := new(StructDefaultArgs)
+	err := xml2RPC("<methodResponse><params><param><value><struct><member><name>mode</name><value><string>manual</string></value></member><member><name>enabled</name><value><boolean>0</boolean></value></member></struct></value></param><param><value><struct></struct></value></param></params></methodResponse>", req)
+	if err != nil {
+		t.Error("XML2RPC conversion failed", err)
+	}
+	expected_req := &StructDefaultArgs{
+		StructDefaultXml2Rpc{7, 0.5, "manual", false},
+		StructDefaultXml2Rpc{7, 0.5, "auto", true},
+	}
+	if !reflect.DeepEqual(req, expected_req) {
+		t.Error("XML2RPC conversion failed")
+		t.Error("Expected", expected_req)
+		t.Error("Got", req)
+	}
+}
+
+type EmbeddedDefaults struct {
+	Retries int    `xmlrpc:"retries,default=3"`
+	Mode    string `xmlrpc:"mode,default=embedded"`
+}
+
+type StructEmbeddedDefaults struct {
+	EmbeddedDefaults
+	Mode string `xmlrpc:"mode,default=auto"`
+}
+
+func TestDecodeEmbeddedDefault(t *testing.T) {
+	req := new(struct{ A, B StructEmbeddedDefaults })
+	err := xml2RPC("<methodResponse><params><param><value><struct></struct></value></param><param><value><struct><member><name>retries</name><value><int>5</int></value></member></struct></value></param></params></methodResponse>", req)
+	if err != nil {
+		t.Error("XML2RPC conversion failed", err)
+	}
+	// The hidden mode of the embedded struct has no default.
+	expected := StructEmbeddedDefaults{EmbeddedDefaults{3, ""}, "auto"}
+	if req.A != expected {
+		t.Errorf("expected %+v, but got %+v", expected, req.A)
+	}
+	expected.Retries = 5
+	if req.B != expected {
+		t.Errorf("expected %+v, but got %+v", expected, req.B)
+	}
+}
+
+type StructLayoutXml2Rpc struct {
+	Start time.Time `xmlrpc:"date_start,layout=2006-01-02"`
+	Write time.Time `xmlrpc:"write_date,layout=2006-01-02 15:04:05"`
+	End   time.Time `xmlrpc:"date_end,layout=2006-01-02,omitempty"`
+}
+
+type StructLayoutArgs struct {
+	Task StructLayoutXml2Rpc
+}
+
+func TestDateLayout(t *testing.T) {
+	req := new(StructLayoutArgs)
+	err := xml2RPC("<methodResponse><params><param><value><struct><member><name>date_start</name><value><string>2024-03-15</string></value></member><member><name>write_date</name><value>2024-03-15 10:22:01</value></member><member><name>date_end</name><value><string></string></value></member></struct></value></param></params></methodResponse>", req)
+	if err != nil {
+		t.Error("XML2RPC conversion failed", err)
+	}
+	expected_req := &StructLayoutArgs{StructLayoutXml2Rpc{
+		time.Date(2024, 3, 15, 0, 0, 0, 0, time.Local),
+		time.Date(2024, 3, 15, 10, 22, 1, 0, time.Local),
+		time.Time{},
+	}}
+	if !reflect.DeepEqual(req, expected_req) {
+		t.Error("XML2RPC conversion failed")
+		t.Error("Expected", expected_req)
+		t.Error("Got", req)
+	}
+
+	xml, err := rpcResponse2XML(expected_req)
+	if err != nil {
+		t.Error("RPC2XML conversion failed", err)
+	}
+	expected := "<methodResponse><params><param><value><struct><member><name>date_start</name><value><string>2024-03-15</string></value></member><member><name>write_date</name><value><string>2024-03-15 10:22:01</string></value></member><member><name>date_end</name><value><string></string></value></member></struct></value></param></params></methodResponse>"
+	if xml != expected {
+		t.Error("RPC2XML conversion failed")
+		t.Error("Expected", expected)
+		t.Error("Got", xml)
+	}
+
+	err = xml2RPC("<methodResponse><params><param><value><struct><member><name>date_start</name><value><string></string></value></member></struct></value></param></params></methodResponse>", req)
+	if err == nil {
+		t.Error("expected error for empty date without omitempty, but got nil")
+	}
+}
+
+type StructLayoutParams struct {
+	Day   time.Time `xmlrpc:"day,layout=2006-01-02"`
+	Until time.Time `xmlrpc:"until,layout=2006-01-02,omitempty"`
+}
+
+func TestDateLayoutParams(t *testing.T) {
+	req := &StructLayoutParams{Day: time.Date(2024, 3, 15, 0, 0, 0, 0, time.Local)}
+	xml, err := rpcRequest2XML("Some.Method", req)
+	if err != nil {
+		t.Error("RPC2XML conversion failed", err)
+	}
+	expected := "<methodCall><methodName>Some.Method</methodName><params><param><value><string>2024-03-15</string></value></param><param><value><string></string></value></param></params></methodCall>"
+	if xml != expected {
+		t.Error("RPC2XML conversion failed")
+		t.Error("Expected", expected)
+		t.Error("Got", xml)
+	}
+
+	decoded := new(StructLayoutParams)
+	if _, err := DecodeServerRequest(strings.NewReader(xml), decoded); err != nil {
+		t.Error("Expected err to be nil, but got:", err)
+	}
+	if !reflect.DeepEqual(decoded, req) {
+		t.Errorf("expected %+v, but got %+v", req, decoded)
+	}
+}
+
+func TestCaseInsensitiveFields(t *testing.T) {
+	type Contact struct {
+		EmailAddr string
+		Phone     string `xmlrpc:"phone"`
+	}
+	type User struct {
+		Username string
+		Name     string `xmlrpc:"Name"`
+		NAME     string `xmlrpc:"NAME"`
+		Contact  Contact
+		Extra    map[string]string
+	}
+	data := "<methodResponse><params><param><value><struct><member><name>USERNAME</name><value>jdoe</value></member><member><name>NAME</name><value>exact</value></member><member><name>contact</name><value><struct><member><name>EMAILADDR</name><value>j@example.com</value></member><member><name>Phone</name><value>555</value></member></struct></value></member><member><name>extra</name><value><struct><member><name>KeepCase</name><value>x</value></member></struct></value></member></struct></value></param></params></methodResponse>"
+
+	var res struct{ User User }
+	if err := NewDecoder(CaseInsensitiveFields()).DecodeClientResponse(strings.NewReader(data), &res); err != nil {
+		t.Error("Expected err to be nil, but got:", err)
+	}
+	expected := User{
+		Username: "jdoe",
+		NAME:     "exact",
+		Contact:  Contact{EmailAddr: "j@example.com", Phone: "555"},
+		Extra:    map[string]string{"KeepCase": "x"},
+	}
+	if !reflect.DeepEqual(res.User, expected) {
+		t.Errorf("expected %+v, but got %+v", expected, res.User)
+	}
+
+	var plain struct{ User User }
+	if err := DecodeClientResponse(strings.NewReader(data), &plain); err != nil {
+		t.Error("Expected err to be nil, but got:", err)
+	}
+	if plain.User.Username != "" || plain.User.Contact.EmailAddr != "" {
+		t.Errorf("expected case to matter by default, but got %+v", plain.User)
+	}
+
+	ambiguous := "<methodResponse><params><param><value><struct><member><name>nAmE</name><value>who</value></member></struct></value></param></params></methodResponse>"
+	err := NewDecoder(CaseInsensitiveFields()).DecodeClientResponse(strings.NewReader(ambiguous), &res)
+	if err == nil || !strings.Contains(err.Error(), "matches fields Name and NAME") {
+		t.Errorf("expected ambiguous member error, but got %v", err)
+	}
+}
+
+func TestCaseInsensitiveEmbeddedFields(t *testing.T) {
+	type Audit struct {
+		CreatedBy string
+		Note      string
+	}
+	type Labels struct {
+		Note string `xmlrpc:"NOTE"`
+	}
+	type Entry struct {
+		*Audit
+		Title string
+		Note  string
+	}
+	data := "<methodResponse><params><param><value><struct><member><name>createdby</name><value>ada</value></member><member><name>TITLE</name><value>t</value></member><member><name>note</name><value>outer</value></member></struct></value></param></params></methodResponse>"
+	res := new(struct{ Entry Entry })
+	if err := NewDecoder(CaseInsensitiveFields()).DecodeClientResponse(strings.NewReader(data), res); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if res.Entry.Audit == nil || res.Entry.CreatedBy != "ada" || res.Entry.Title != "t" || res.Entry.Note != "outer" || res.Entry.Audit.Note != "" {
+		t.Errorf("expected the embedded CreatedBy and the outer Note to be set, but got %+v and %+v", res.Entry, res.Entry.Audit)
+	}
+
+	type Both struct {
+		Audit
+		Labels
+	}
+	data = "<methodResponse><params><param><value><struct><member><name>nOtE</name><value>who</value></member></struct></value></param></params></methodResponse>"
+	err := NewDecoder(CaseInsensitiveFields()).DecodeClientResponse(strings.NewReader(data), new(struct{ Both Both }))
+	if err == nil || !strings.Contains(err.Error(), "matches fields Audit.Note and Labels.Note") {
+		t.Errorf("expected ambiguous member error across embedded structs, but got %v", err)
+	}
+}
+
+func TestLowercaseMemberNames(t *testing.T) {
+	type user struct {
+		Login string
+		Name  string `xmlrpc:"full_name"`
+	}
+	data := "<methodResponse><params><param><value><struct><member><name>login</name><value>ada</value></member><member><name>name</name><value>Ada</value></member><member><name>full_name</name><value>Ada Lovelace</value></member></struct></value></param></params></methodResponse>"
+	res := new(struct{ User user })
+	extras, err := NewDecoder().DecodeWithExtras(strings.NewReader(data), res)
+	if err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	// Fields renamed by a tag aren't matched by their Go name.
+	if res.User.Login != "ada" || res.User.Name != "Ada Lovelace" {
+		t.Errorf("expected ada and Ada Lovelace, but got %+v", res.User)
+	}
+	if !reflect.DeepEqual(extras, map[string]interface{}{"params[0].name": "Ada"}) {
+		t.Errorf("expected the name member to be extra, but got %v", extras)
+	}
+}
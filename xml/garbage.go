@@ -0,0 +1,73 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// documentStarts are the markers SkipLeadingGarbage looks for.
+var documentStarts = [][]byte{[]byte("<?xml"), []byte("<methodResponse")}
+
+// SkipLeadingGarbage makes the decoder skip up to limit bytes before the
+// XML declaration or the <methodResponse> element of a response, such as
+// the warnings some PHP servers print before the document. The skipped
+// bytes are reported to the warning handler as a *GarbageError. Strict
+// mode ignores the option.
+func SkipLeadingGarbage(limit int) Option {
+	return func(o *options) {
+		o.garbageLimit = limit
+	}
+}
+
+// GarbageError holds the bytes skipped before a response document.
+type GarbageError struct {
+	Data []byte
+}
+
+func (e *GarbageError) Error() string {
+	return fmt.Sprintf("skipped %d bytes before the document: %q", len(e.Data), e.Data)
+}
+
+// garbageLen returns the length of the garbage data starts with, if the
+// document starts within the configured limit.
+func (d *Decoder) garbageLen(data []byte) int {
+	limit := d.opts.garbageLimit
+	if limit <= 0 || d.opts.strict {
+		return 0
+	}
+	n := -1
+	for _, start := range documentStarts {
+		if i := bytes.Index(data, start); i >= 0 && (n < 0 || i < n) {
+			n = i
+		}
+	}
+	if n <= 0 || n > limit {
+		return 0
+	}
+	d.warn(&GarbageError{Data: append([]byte(nil), data[:n]...)})
+	return n
+}
+
+// skipGarbage returns data without the garbage it starts with.
+func (d *Decoder) skipGarbage(data []byte) []byte {
+	return data[d.garbageLen(data):]
+}
+
+// skipGarbageReader returns a reader of r without the garbage it starts
+// with.
+func (d *Decoder) skipGarbageReader(r io.Reader) io.Reader {
+	if d.opts.garbageLimit <= 0 || d.opts.strict {
+		return r
+	}
+	size := d.opts.garbageLimit + len("<methodResponse")
+	br := bufio.NewReaderSize(r, size)
+	data, _ := br.Peek(size)
+	br.Discard(d.garbageLen(data))
+	return br
+}
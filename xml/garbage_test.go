@@ -0,0 +1,65 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSkipLeadingGarbage(t *testing.T) {
+	notice := "<br />\n<b>Notice</b>:  Undefined variable: user in <b>/var/www/xmlrpc.php</b> on line <b>12</b><br />\n"
+	tests := []struct {
+		data string
+		skip string
+	}{
+		{notice + `<?xml version="1.0"?><methodResponse><params><param><value><int>42</int></value></param></params></methodResponse>`, notice},
+		{notice + `<methodResponse><params><param><value><int>42</int></value></param></params></methodResponse>`, notice},
+		{`<methodResponse><params><param><value><int>42</int></value></param></params></methodResponse>`, ""},
+	}
+	for _, test := range tests {
+		for _, stream := range []bool{false, true} {
+			var skipped []string
+			d := NewDecoder(SkipLeadingGarbage(1024), WithWarningHandler(func(err error) {
+				var garbage *GarbageError
+				if errors.As(err, &garbage) {
+					skipped = append(skipped, string(garbage.Data))
+				}
+			}))
+			var reply struct{ N int }
+			var err error
+			if stream {
+				err = d.DecodeAuto(strings.NewReader(test.data), 0, &reply)
+			} else {
+				err = d.DecodeClientResponse(strings.NewReader(test.data), &reply)
+			}
+			if err != nil {
+				t.Errorf("stream %v: expected err to be nil, but got %v", stream, err)
+			}
+			if reply.N != 42 {
+				t.Errorf("stream %v: expected 42, but got %d", stream, reply.N)
+			}
+			if test.skip == "" && len(skipped) != 0 {
+				t.Errorf("stream %v: expected nothing skipped, but got %q", stream, skipped)
+			}
+			if test.skip != "" && (len(skipped) != 1 || skipped[0] != test.skip) {
+				t.Errorf("stream %v: expected %q skipped, but got %q", stream, test.skip, skipped)
+			}
+		}
+	}
+
+	data := tests[0].data
+	var reply struct{ N int }
+	for _, d := range []*Decoder{
+		NewDecoder(),
+		NewDecoder(SkipLeadingGarbage(16)),
+		NewDecoder(SkipLeadingGarbage(1024), Strict()),
+	} {
+		if err := d.DecodeClientResponse(strings.NewReader(data), &reply); err == nil {
+			t.Error("expected error, but got nil")
+		}
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"strings"
+)
+
+// ResponseInterceptor rewrites the response of a Codec to a call of method
+// before it is written. response is the value of the param of the
+// response, or of the fault if fault is true, and can be modified in
+// place. Returning an error replaces the response with a fault: the
+// error itself if it is a Fault, or an application error otherwise.
+type ResponseInterceptor func(method string, response *Value, fault bool) error
+
+// WithResponseInterceptor makes a Codec pass every response through
+// intercept, such as to sign or encrypt it. Interceptors are applied in
+// the order they are given.
+func WithResponseInterceptor(intercept ResponseInterceptor) Option {
+	return func(o *options) {
+		o.interceptors = append(o.interceptors, intercept)
+	}
+}
+
+// interceptResponse applies the response interceptors to the
+// methodResponse xmlraw, returning the response to write.
+func (e *Encoder) interceptResponse(method, xmlraw string) string {
+	doc, err := parseDocument(strings.NewReader(xmlraw))
+	if err != nil {
+		return e.fault2XML(errorFault(err))
+	}
+	fault := doc.Fault != nil
+	response := doc.Fault
+	if !fault {
+		if len(doc.Params) == 0 {
+			return xmlraw
+		}
+		response = &doc.Params[0].Value
+	}
+	for _, intercept := range e.opts.interceptors {
+		if err := intercept(method, response, fault); err != nil {
+			return e.fault2XML(errorFault(err))
+		}
+	}
+
+	var xml string
+	if fault {
+		if xml, err = e.value2XML(*response); err == nil {
+			xml = "<fault>" + xml + "</fault>"
+		}
+	} else {
+		params := make([]Value, len(doc.Params))
+		for i, p := range doc.Params {
+			params[i] = p.Value
+		}
+		xml, err = e.params2XML(params)
+	}
+	if err != nil {
+		return e.fault2XML(errorFault(err))
+	}
+	return e.root("methodResponse") + xml + "</methodResponse>"
+}
@@ -0,0 +1,127 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/rpc"
+)
+
+type FailingService struct {
+}
+
+func (t *FailingService) Fail(r *http.Request, req *Service1Request, res *Service1Response) error {
+	return Fault{Code: 7, String: "Failed"}
+}
+
+func TestResponseInterceptor(t *testing.T) {
+	type interception struct {
+		method string
+		fault  bool
+	}
+	var calls []interception
+	s := rpc.NewServer()
+	s.RegisterCodec(NewCodec(WithResponseInterceptor(func(method string, response *Value, fault bool) error {
+		calls = append(calls, interception{method, fault})
+		if method == "FailingService.Fail" && !fault {
+			t.Error("expected fault response to be flagged")
+		}
+		if method == "Service1.Multiply" {
+			return errors.New("refused")
+		}
+		response.Struct = append(response.Struct, Member{Name: "Signature", Value: Value{String: "signed"}})
+		return nil
+	})), "text/xml")
+	s.RegisterService(new(Service3), "")
+	s.RegisterService(new(FailingService), "")
+	s.RegisterService(new(Service1), "")
+
+	var res struct {
+		Result struct {
+			Facebook  string
+			Signature string
+		}
+	}
+	if err := call(s, "Service3.GetInfo", &Service3Request{Person{Name: "Johnny"}}, &res); err != nil {
+		t.Error("Expected err to be nil, but got:", err)
+	}
+	if res.Result.Facebook != "http://facebook.com/Johnny" || res.Result.Signature != "signed" {
+		t.Errorf("expected signed response, but got %+v", res.Result)
+	}
+
+	var res1 Service1Response
+	err := call(s, "Service1.Multiply", &Service1Request{4, 2}, &res1)
+	if fault, ok := err.(Fault); !ok || fault.Code != FaultApplicationError.Code {
+		t.Errorf("expected application error, but got %v", err)
+	}
+
+	var faultRes Service1Response
+	err = call(s, "FailingService.Fail", &Service1Request{1, 2}, &faultRes)
+	fault, ok := err.(Fault)
+	if !ok || fault.Code != 7 || fault.Detail()["Signature"] != "signed" {
+		t.Errorf("expected signed fault 7, but got %#v", err)
+	}
+
+	calls = nil
+	s2 := rpc.NewServer()
+	s2.RegisterCodec(NewCodec(WithResponseInterceptor(func(method string, response *Value, fault bool) error {
+		calls = append(calls, interception{method, fault})
+		response.Int = "100"
+		return nil
+	})), "text/xml")
+	s2.RegisterService(new(Service1), "")
+	if err := call(s2, "Service1.Multiply", &Service1Request{4, 2}, &res1); err != nil {
+		t.Error("Expected err to be nil, but got:", err)
+	}
+	if res1.Result != 100 {
+		t.Errorf("expected rewritten result 100, but got %d", res1.Result)
+	}
+	if len(calls) != 1 || calls[0] != (interception{"Service1.Multiply", false}) {
+		t.Errorf("expected one call for Service1.Multiply, but got %v", calls)
+	}
+}
+
+func TestInterceptedEncoding(t *testing.T) {
+	reply := &struct {
+		Result struct {
+			Note string
+			ID   int64
+			Next *string
+		}
+	}{}
+	reply.Result.Note = "<a> & ]]> b"
+	reply.Result.ID = 9007199254740993
+	keep := func(method string, response *Value, fault bool) error { return nil }
+	for _, opt := range []Option{CDATAStrings(), ApacheExtensions(), WithInvalidChars(InvalidCharsEscape)} {
+		e := NewEncoder(opt, WithResponseInterceptor(keep))
+		expected, err := e.rpcResponse2XML(reply)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if xml := e.interceptResponse("Some.Method", expected); xml != expected {
+			t.Error("Expected", expected)
+			t.Error("Got", xml)
+		}
+	}
+
+	add := func(method string, response *Value, fault bool) error {
+		response.Struct = append(response.Struct, Member{Name: "Log", Value: Value{String: "bell\x07"}})
+		return nil
+	}
+	xml := NewEncoder(WithInvalidChars(InvalidCharsStrip), WithResponseInterceptor(add)).interceptResponse("Some.Method", "<methodResponse><params><param><value><struct></struct></value></param></params></methodResponse>")
+	expected := "<methodResponse><params><param><value><struct><member><name>Log</name><value><string>bell</string></value></member></struct></value></param></params></methodResponse>"
+	if xml != expected {
+		t.Error("Expected", expected)
+		t.Error("Got", xml)
+	}
+	xml = NewEncoder(WithInvalidChars(InvalidCharsReject), WithResponseInterceptor(add)).interceptResponse("Some.Method", "<methodResponse><params><param><value><struct></struct></value></param></params></methodResponse>")
+	if !strings.Contains(xml, "<fault>") || !strings.Contains(xml, "params[0].Log") {
+		t.Errorf("expected fault for the invalid character at params[0].Log, but got %s", xml)
+	}
+}
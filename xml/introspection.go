@@ -0,0 +1,273 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/rpc"
+)
+
+// MethodDoc documents a method served by an XML-RPC server.
+type MethodDoc struct {
+	Name      string
+	Help      string
+	Signature []string // return type, followed by the parameter types
+	Params    []ParamDoc
+	Returns   []ParamDoc
+}
+
+// ParamDoc documents a parameter, a returned value or a struct member.
+//
+// Help and Type are taken from the struct tag of the corresponding field,
+// as in `xmlrpc:"user_id,type=i8,help=numeric id of the user"`. Help may
+// also be given in a separate `xmlrpchelp:"..."` tag, which is the only
+// way to include commas.
+//
+// The members of structs are documented in Members, except for recursive
+// types, such as the parent of a tree node, whose Type is the name of the
+// Go type instead.
+type ParamDoc struct {
+	Name    string
+	Type    string
+	Help    string
+	Members []ParamDoc
+}
+
+// Docs is the documentation of a set of methods, sorted by name.
+type Docs []MethodDoc
+
+// Introspection is an RPC service implementing the system.listMethods,
+// system.methodSignature and system.methodHelp introspection methods for
+// the methods registered with it. Use Codec.RegisterIntrospection to
+// serve it.
+type Introspection struct {
+	mu      sync.RWMutex
+	methods map[string]MethodDoc
+}
+
+// NewIntrospection returns a new Introspection service.
+func NewIntrospection() *Introspection {
+	return &Introspection{methods: make(map[string]MethodDoc)}
+}
+
+// Register documents method, taking its signature from args and reply,
+// which are the argument and reply types of the service method.
+func (s *Introspection) Register(method, help string, args, reply interface{}) {
+	doc := MethodDoc{
+		Name:    method,
+		Help:    help,
+		Params:  structDocs(reflect.TypeOf(args)),
+		Returns: structDocs(reflect.TypeOf(reply)),
+	}
+	ret := "nil"
+	if len(doc.Returns) != 0 {
+		ret = doc.Returns[0].Type
+	}
+	doc.Signature = append(doc.Signature, ret)
+	for _, param := range doc.Params {
+		doc.Signature = append(doc.Signature, param.Type)
+	}
+
+	s.mu.Lock()
+	s.methods[method] = doc
+	s.mu.Unlock()
+}
+
+// Docs returns the documentation of all registered methods.
+func (s *Introspection) Docs() Docs {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	docs := make(Docs, 0, len(s.methods))
+	for _, doc := range s.methods {
+		docs = append(docs, doc)
+	}
+	sort.Slice(docs, func(i, j int) bool { return docs[i].Name < docs[j].Name })
+	return docs
+}
+
+func (s *Introspection) lookup(method string) (MethodDoc, error) {
+	s.mu.RLock()
+	doc, ok := s.methods[method]
+	s.mu.RUnlock()
+	if !ok {
+		fault := FaultInvalidParams
+		fault.String += fmt.Sprintf(": unknown method %q", method)
+		return doc, fault
+	}
+	return doc, nil
+}
+
+// IntrospectionArgs holds the method name passed to system.methodSignature
+// and system.methodHelp.
+type IntrospectionArgs struct {
+	Method string
+}
+
+// ListMethods implements system.listMethods.
+func (s *Introspection) ListMethods(r *http.Request, args *struct{}, reply *struct{ Methods []string }) error {
+	for _, doc := range s.Docs() {
+		reply.Methods = append(reply.Methods, doc.Name)
+	}
+	return nil
+}
+
+// MethodSignature implements system.methodSignature.
+func (s *Introspection) MethodSignature(r *http.Request, args *IntrospectionArgs, reply *struct{ Signatures [][]string }) error {
+	doc, err := s.lookup(args.Method)
+	if err != nil {
+		return err
+	}
+	reply.Signatures = [][]string{doc.Signature}
+	return nil
+}
+
+// MethodHelp implements system.methodHelp.
+func (s *Introspection) MethodHelp(r *http.Request, args *IntrospectionArgs, reply *struct{ Help string }) error {
+	doc, err := s.lookup(args.Method)
+	if err != nil {
+		return err
+	}
+	help := doc.Help
+	for _, param := range doc.Params {
+		help += fmt.Sprintf("\n%s (%s)", param.Name, param.Type)
+		if param.Help != "" {
+			help += ": " + param.Help
+		}
+	}
+	reply.Help = strings.TrimPrefix(help, "\n")
+	return nil
+}
+
+// RegisterIntrospection registers the introspection service with s under
+// the "system" name, and aliases the standard lower case method names.
+func (c *Codec) RegisterIntrospection(s *rpc.Server, in *Introspection) error {
+	if err := s.RegisterService(in, "system"); err != nil {
+		return err
+	}
+	c.RegisterAlias("system.listMethods", "system.ListMethods")
+	c.RegisterAlias("system.methodSignature", "system.MethodSignature")
+	c.RegisterAlias("system.methodHelp", "system.MethodHelp")
+	return nil
+}
+
+// Markdown renders the documentation as a Markdown document.
+func (docs Docs) Markdown() string {
+	var b strings.Builder
+	for _, doc := range docs {
+		params := make([]string, len(doc.Params))
+		for i, param := range doc.Params {
+			params[i] = param.Type + " " + param.Name
+		}
+		fmt.Fprintf(&b, "## %s\n\n`%s %s(%s)`\n\n", doc.Name, doc.Signature[0], doc.Name, strings.Join(params, ", "))
+		if doc.Help != "" {
+			fmt.Fprintf(&b, "%s\n\n", doc.Help)
+		}
+		if len(doc.Params) != 0 {
+			b.WriteString("| Parameter | Type | Description |\n| --- | --- | --- |\n")
+			writeParamRows(&b, doc.Params, "")
+			b.WriteString("\n")
+		}
+		if len(doc.Returns) != 0 {
+			b.WriteString("| Returns | Type | Description |\n| --- | --- | --- |\n")
+			writeParamRows(&b, doc.Returns, "")
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+func writeParamRows(b *strings.Builder, params []ParamDoc, prefix string) {
+	for _, param := range params {
+		fmt.Fprintf(b, "| %s%s | %s | %s |\n", prefix, param.Name, param.Type, param.Help)
+		writeParamRows(b, param.Members, prefix+param.Name+".")
+	}
+}
+
+// structDocs documents the fields of the struct t points to.
+func structDocs(t reflect.Type) []ParamDoc {
+	return typeDocs(t, make(map[reflect.Type]bool))
+}
+
+// typeDocs is structDocs, where visited holds the structs being
+// documented, which are named rather than documented again.
+func typeDocs(t reflect.Type, visited map[reflect.Type]bool) []ParamDoc {
+	if t == nil {
+		return nil
+	}
+	t = indirect(t)
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	visited[t] = true
+	defer delete(visited, t)
+	var docs []ParamDoc
+	structFields(t).visit(t, func(_ []int, f reflect.StructField, info fieldInfo) {
+		doc := ParamDoc{
+			Name: info.name,
+			Type: info.opts["type"],
+			Help: info.opts["help"],
+		}
+		if help := f.Tag.Get("xmlrpchelp"); help != "" {
+			doc.Help = help
+		}
+		if doc.Type == "" {
+			doc.Type = typeName(f.Type)
+		}
+		if st := indirect(f.Type); doc.Type == "struct" && visited[st] {
+			// A recursive type, such as a tree node
+			doc.Type = st.Name()
+		} else if doc.Type == "struct" {
+			doc.Members = typeDocs(f.Type, visited)
+		}
+		docs = append(docs, doc)
+	})
+	return docs
+}
+
+// indirect returns the type t points to, through any number of pointers.
+func indirect(t reflect.Type) reflect.Type {
+	seen := make(map[reflect.Type]bool)
+	for t.Kind() == reflect.Ptr && !seen[t] {
+		seen[t] = true
+		t = t.Elem()
+	}
+	return t
+}
+
+// typeName returns the XML-RPC type values of type t are encoded as.
+func typeName(t reflect.Type) string {
+	t = indirect(t)
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "int"
+	case reflect.Float32, reflect.Float64:
+		return "double"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.String:
+		return "string"
+	case reflect.Struct:
+		if t == reflect.TypeOf(time.Time{}) {
+			return "dateTime.iso8601"
+		}
+		return "struct"
+	case reflect.Map:
+		return "struct"
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "base64"
+		}
+		return "array"
+	}
+	return "any"
+}
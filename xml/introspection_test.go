@@ -0,0 +1,112 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/gorilla/rpc"
+)
+
+type IntrospectionUser struct {
+	ID   int64  `xmlrpc:"user_id,type=i8,help=numeric id of the user"`
+	Name string `xmlrpc:"name" xmlrpchelp:"login name, in lower case"`
+}
+
+type IntrospectionRequest struct {
+	User  IntrospectionUser `xmlrpc:"user"`
+	Limit int               `xmlrpc:"limit,help=maximum number of results"`
+}
+
+type IntrospectionResponse struct {
+	Groups []string
+}
+
+type IntrospectionService struct{}
+
+func (s *IntrospectionService) Groups(r *http.Request, req *IntrospectionRequest, res *IntrospectionResponse) error {
+	return nil
+}
+
+func TestIntrospection(t *testing.T) {
+	in := NewIntrospection()
+	in.Register("IntrospectionService.Groups", "Lists the groups of a user.", &IntrospectionRequest{}, &IntrospectionResponse{})
+
+	s := rpc.NewServer()
+	codec := NewCodec()
+	s.RegisterCodec(codec, "text/xml")
+	s.RegisterService(new(IntrospectionService), "")
+	if err := codec.RegisterIntrospection(s, in); err != nil {
+		t.Fatal(err)
+	}
+
+	var methods struct{ Methods []string }
+	if err := call(s, "system.listMethods", &struct{}{}, &methods); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if !reflect.DeepEqual(methods.Methods, []string{"IntrospectionService.Groups"}) {
+		t.Errorf("Wrong methods: %v", methods.Methods)
+	}
+
+	var signatures struct{ Signatures [][]string }
+	if err := call(s, "system.methodSignature", &IntrospectionArgs{"IntrospectionService.Groups"}, &signatures); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if !reflect.DeepEqual(signatures.Signatures, [][]string{{"array", "struct", "int"}}) {
+		t.Errorf("Wrong signatures: %v", signatures.Signatures)
+	}
+
+	var help struct{ Help string }
+	if err := call(s, "system.methodHelp", &IntrospectionArgs{"IntrospectionService.Groups"}, &help); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if help.Help != "Lists the groups of a user.\nuser (struct)\nlimit (int): maximum number of results" {
+		t.Errorf("Wrong help: %q", help.Help)
+	}
+
+	expected := "## IntrospectionService.Groups\n\n" +
+		"`array IntrospectionService.Groups(struct user, int limit)`\n\n" +
+		"Lists the groups of a user.\n\n" +
+		"| Parameter | Type | Description |\n| --- | --- | --- |\n" +
+		"| user | struct |  |\n" +
+		"| user.user_id | i8 | numeric id of the user |\n" +
+		"| user.name | string | login name, in lower case |\n" +
+		"| limit | int | maximum number of results |\n\n" +
+		"| Returns | Type | Description |\n| --- | --- | --- |\n" +
+		"| Groups | array |  |\n\n"
+	if md := in.Docs().Markdown(); md != expected {
+		t.Errorf("Wrong markdown:\n%s\nexpected:\n%s", md, expected)
+	}
+}
+
+func TestIntrospectionDocs(t *testing.T) {
+	in := NewIntrospection()
+	in.Register("Nodes.save", "", &struct{ Node JavaNode }{}, &struct{ Entry StructEmbedding }{})
+	docs := in.Docs()
+	if len(docs) != 1 {
+		t.Fatalf("expected one method, but got %v", docs)
+	}
+	params := []ParamDoc{{Name: "Node", Type: "struct", Members: []ParamDoc{
+		{Name: "id", Type: "int"},
+		{Name: "parent", Type: "JavaNode"},
+		{Name: "name", Type: "string"},
+	}}}
+	if !reflect.DeepEqual(docs[0].Params, params) {
+		t.Errorf("expected %+v, but got %+v", params, docs[0].Params)
+	}
+	// The members of embedded structs are promoted, unless hidden.
+	returns := []ParamDoc{{Name: "Entry", Type: "struct", Members: []ParamDoc{
+		{Name: "id", Type: "int"},
+		{Name: "owner", Type: "string"},
+		{Name: "Author", Type: "string"},
+		{Name: "tagged", Type: "struct", Members: []ParamDoc{{Name: "id", Type: "int"}, {Name: "created", Type: "string"}}},
+		{Name: "created", Type: "string"},
+	}}}
+	if !reflect.DeepEqual(docs[0].Returns, returns) {
+		t.Errorf("expected %+v, but got %+v", returns, docs[0].Returns)
+	}
+}
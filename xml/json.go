@@ -0,0 +1,27 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+var rawMessageType = reflect.TypeOf(json.RawMessage(nil))
+
+// ValueToJSON converts v to JSON: structs become objects, arrays become
+// arrays, base64 data becomes a base64 string and dateTime values become
+// RFC 3339 strings.
+func ValueToJSON(v Value) ([]byte, error) {
+	return NewDecoder(defaults...).valueToJSON(v)
+}
+
+func (d *Decoder) valueToJSON(v Value) ([]byte, error) {
+	val, err := d.value2Interface(v)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(val)
+}
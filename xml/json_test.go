@@ -0,0 +1,51 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestXML2RPCRawMessage(t *testing.T) {
+	req := new(struct {
+		Event struct {
+			Name    string          `xmlrpc:"name"`
+			Payload json.RawMessage `xmlrpc:"payload"`
+		}
+	})
+	err := xml2RPC("<methodResponse><params><param><value><struct><member><name>name</name><value><string>deploy</string></value></member><member><name>payload</name><value><struct><member><name>id</name><value><int>7</int></value></member><member><name>tags</name><value><array><data><value>a</value><value><string>b</string></value></data></array></value></member><member><name>ok</name><value><boolean>1</boolean></value></member><member><name>ratio</name><value><double>0.5</double></value></member></struct></value></member></struct></value></param></params></methodResponse>", req)
+	if err != nil {
+		t.Error("XML2RPC conversion failed", err)
+	}
+	if req.Event.Name != "deploy" {
+		t.Errorf("expected deploy, but got %q", req.Event.Name)
+	}
+
+	var payload interface{}
+	if err := json.Unmarshal(req.Event.Payload, &payload); err != nil {
+		t.Errorf("expected valid JSON, but got %q: %v", req.Event.Payload, err)
+	}
+	expected := map[string]interface{}{
+		"id":    7.0,
+		"tags":  []interface{}{"a", "b"},
+		"ok":    true,
+		"ratio": 0.5,
+	}
+	if !reflect.DeepEqual(payload, expected) {
+		t.Errorf("expected %v, but got %v", expected, payload)
+	}
+}
+
+func TestValueToJSON(t *testing.T) {
+	data, err := ValueToJSON(Value{Base64: "aGVsbG8="})
+	if err != nil {
+		t.Error("ValueToJSON failed", err)
+	}
+	if string(data) != `"aGVsbG8="` {
+		t.Errorf("expected \"aGVsbG8=\", but got %s", data)
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// elementNames maps the lowercase names of the elements of XML-RPC, such
+// as "datetime.iso8601", to their names.
+var elementNames = func() map[string]string {
+	names := make(map[string]string, len(parents))
+	for name := range parents {
+		names[strings.ToLower(name)] = name
+	}
+	return names
+}()
+
+// canonicalNames returns xmlraw with the names of the XML-RPC elements
+// written in any case, such as <Int> or <STRING>, written as in the
+// specification.
+func canonicalNames(xmlraw []byte) []byte {
+	out, _ := ioutil.ReadAll(&nameFilter{r: bytes.NewReader(xmlraw)})
+	return out
+}
+
+// rawCharsetReader returns r as it is, so that the offsets of the tokens
+// of documents in any charset are offsets into their bytes.
+func rawCharsetReader(label string, r io.Reader) (io.Reader, error) {
+	return r, nil
+}
+
+// nameFilter reads from r, writing the names of the XML-RPC elements as
+// canonicalNames does. The document is read token by token, so that
+// character data, including CDATA sections, comments and processing
+// instructions are written as they are. After a syntax error, the rest
+// is written as it is, for the decoder to report the error.
+type nameFilter struct {
+	r       io.Reader
+	decoder *xml.Decoder
+	read    []byte // the bytes read from r and not yet written
+	offset  int64  // the offset of read in the document
+	readErr error
+	raw     bool // whether the rest is written as it is
+	out     []byte
+	err     error
+}
+
+func (f *nameFilter) Read(p []byte) (int, error) {
+	if f.decoder == nil {
+		f.decoder = xml.NewDecoder(nameSource{f})
+		f.decoder.CharsetReader = rawCharsetReader
+	}
+	for len(f.out) == 0 && f.err == nil {
+		f.next()
+	}
+	n := copy(p, f.out)
+	f.out = f.out[n:]
+	if len(f.out) == 0 {
+		return n, f.err
+	}
+	return n, nil
+}
+
+// next sets out to the bytes of the next token, with its name written as
+// in the specification.
+func (f *nameFilter) next() {
+	if f.raw {
+		if len(f.read) != 0 {
+			f.out, f.read = f.read, nil
+			return
+		}
+		buf := make([]byte, 4096)
+		n, err := f.r.Read(buf)
+		f.out, f.err = buf[:n], err
+		return
+	}
+	token, err := f.decoder.RawToken()
+	if err != nil {
+		f.out, f.read = f.read, nil
+		if f.readErr != nil {
+			// r was read to its end or failed: all of it has been read.
+			f.err = f.readErr
+		}
+		f.raw = true
+		return
+	}
+	end := f.decoder.InputOffset()
+	data := f.read[:end-f.offset]
+	f.read, f.offset = f.read[end-f.offset:], end
+	f.out = canonicalName(data, token)
+}
+
+// canonicalName returns data, the bytes of token, with the name of the
+// element written as in the specification if token is a tag.
+func canonicalName(data []byte, token xml.Token) []byte {
+	var name xml.Name
+	switch t := token.(type) {
+	case xml.StartElement:
+		name = t.Name
+	case xml.EndElement:
+		name = t.Name
+	default:
+		return data
+	}
+	canonical, ok := elementNames[strings.ToLower(name.Local)]
+	if !ok || canonical == name.Local || len(data) == 0 {
+		// Empty elements, such as <Nil/>, end without bytes of their own.
+		return data
+	}
+	i := 1
+	if data[i] == '/' {
+		i++
+	}
+	if name.Space != "" {
+		i += len(name.Space) + 1
+	}
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:i]...)
+	out = append(out, canonical...)
+	return append(out, data[i+len(name.Local):]...)
+}
+
+// nameSource reads the document of a nameFilter, keeping the bytes read.
+type nameSource struct {
+	f *nameFilter
+}
+
+func (s nameSource) Read(p []byte) (int, error) {
+	n, err := s.f.r.Read(p)
+	s.f.read = append(s.f.read, p[:n]...)
+	if err != nil {
+		s.f.readErr = err
+	}
+	return n, err
+}
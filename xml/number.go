@@ -0,0 +1,108 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Number holds the text of an int, i4, i8 or double value as received, so
+// that it can be converted without losing precision. It is encoded as an
+// int, or as a double if it has a fraction or an exponent.
+type Number string
+
+// Int64 returns the number as an int64.
+func (n Number) Int64() (int64, error) {
+	return strconv.ParseInt(string(n), 10, 64)
+}
+
+// Float64 returns the number as a float64.
+func (n Number) Float64() (float64, error) {
+	return strconv.ParseFloat(string(n), 64)
+}
+
+// String returns the text of the number.
+func (n Number) String() string {
+	return string(n)
+}
+
+var numberType = reflect.TypeOf(Number(""))
+
+// PreserveNumericText makes the decoder keep the text of int, i4, i8 and
+// double values decoded into strings, as it does for Number fields,
+// instead of rejecting them.
+func PreserveNumericText() Option {
+	return func(o *options) {
+		o.preserveNumeric = true
+	}
+}
+
+// numericText returns the text of value if it is a number, as received
+// but for surrounding space.
+func numericText(value Value) (string, bool) {
+	if text, ok := value.Integer(); ok {
+		return strings.TrimSpace(text), true
+	}
+	if value.Double != "" {
+		return strings.TrimSpace(value.Double), true
+	}
+	return "", false
+}
+
+// number2Field sets field to the text of value, if value is a number and
+// field a Number, or a string with numeric text preserved. Integers with
+// leading zeros are rejected in strict mode, as for int fields.
+func (d *Decoder) number2Field(value Value, field reflect.Value) (bool, error) {
+	if field.Type() != numberType && (field.Kind() != reflect.String || !d.opts.preserveNumeric) {
+		return false, nil
+	}
+	text, ok := numericText(value)
+	if !ok {
+		return false, nil
+	}
+	if _, integer := value.Integer(); integer {
+		if err := d.checkInteger(text); err != nil {
+			return true, err
+		}
+	}
+	field.SetString(text)
+	return true, nil
+}
+
+func number2XML(n Number) string {
+	if strings.ContainsAny(string(n), ".eE") {
+		return "<double>" + string(n) + "</double>"
+	}
+	return "<int>" + trimLeadingZeros(string(n)) + "</int>"
+}
+
+// trimLeadingZeros returns text, the text of an integer, without leading
+// zeros, which some servers send and some strict parsers reject.
+func trimLeadingZeros(text string) string {
+	var sign string
+	if strings.HasPrefix(text, "-") || strings.HasPrefix(text, "+") {
+		sign, text = text[:1], text[1:]
+	}
+	digits := strings.TrimLeft(text, "0")
+	if digits == "" && text != "" {
+		digits = "0"
+	}
+	return sign + digits
+}
+
+// checkInteger rejects text, the text of an integer, if it has leading
+// zeros in strict mode. Otherwise they are ignored.
+func (d *Decoder) checkInteger(text string) error {
+	text = strings.TrimSpace(text)
+	if d.opts.strict && trimLeadingZeros(text) != text {
+		fault := FaultInvalidParams
+		fault.String += fmt.Sprintf(": integer %q with leading zeros", text)
+		return fault
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNumber(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"<int>42</int>", "42"},
+		{"<i4> -7 </i4>", "-7"},
+		{"<i8>9223372036854775807</i8>", "9223372036854775807"},
+		{"<ex:i8>-9223372036854775808</ex:i8>", "-9223372036854775808"},
+		{"<double>0.1000000000000000055511151231257827</double>", "0.1000000000000000055511151231257827"},
+		{"<double>1e400</double>", "1e400"},
+	}
+	for _, test := range tests {
+		data := "<methodResponse><params><param><value>" + test.value + "</value></param></params></methodResponse>"
+
+		number := new(struct{ N Number })
+		if err := DecodeClientResponse(strings.NewReader(data), number); err != nil {
+			t.Errorf("%s: expected err to be nil, but got %v", test.value, err)
+		}
+		if string(number.N) != test.expected {
+			t.Errorf("%s: expected Number %q, but got %q", test.value, test.expected, number.N)
+		}
+
+		text := new(struct{ S string })
+		if err := NewDecoder(PreserveNumericText()).DecodeClientResponse(strings.NewReader(data), text); err != nil {
+			t.Errorf("%s: expected err to be nil, but got %v", test.value, err)
+		}
+		if text.S != test.expected {
+			t.Errorf("%s: expected string %q, but got %q", test.value, test.expected, text.S)
+		}
+	}
+
+	if err := DecodeClientResponse(strings.NewReader("<methodResponse><params><param><value><int>42</int></value></param></params></methodResponse>"), new(struct{ S string })); err == nil {
+		t.Error("expected error decoding an int into a string, but got nil")
+	}
+}
+
+func TestNumberMethods(t *testing.T) {
+	if n, err := Number("9223372036854775807").Int64(); err != nil || n != 9223372036854775807 {
+		t.Errorf("expected 9223372036854775807, but got %d, %v", n, err)
+	}
+	if f, err := Number("2.5").Float64(); err != nil || f != 2.5 {
+		t.Errorf("expected 2.5, but got %v, %v", f, err)
+	}
+}
+
+func TestNumber2XML(t *testing.T) {
+	xml, err := rpcRequest2XML("Some.Method", &struct{ A, B Number }{"9223372036854775807", "0.1"})
+	if err != nil {
+		t.Error("RPC2XML conversion failed", err)
+	}
+	expected := "<methodCall><methodName>Some.Method</methodName><params><param><value><int>9223372036854775807</int></value></param><param><value><double>0.1</double></value></param></params></methodCall>"
+	if xml != expected {
+		t.Error("RPC2XML conversion failed")
+		t.Error("Expected", expected)
+		t.Error("Got", xml)
+	}
+}
+
+func TestNumberLeadingZeros(t *testing.T) {
+	tests := []struct {
+		n        Number
+		expected string
+	}{
+		{"007", "<int>7</int>"},
+		{"-007", "<int>-7</int>"},
+		{"000", "<int>0</int>"},
+		{"0", "<int>0</int>"},
+		{"120", "<int>120</int>"},
+		{"0012345678901234567890", "<int>12345678901234567890</int>"},
+	}
+	for _, test := range tests {
+		if xml := number2XML(test.n); xml != test.expected {
+			t.Errorf("%s: expected %s, but got %s", test.n, test.expected, xml)
+		}
+	}
+
+	xml, err := rpcRequest2XML("Some.Method", &struct {
+		A int
+		B int64
+	}{7, 0})
+	if err != nil {
+		t.Error("RPC2XML conversion failed", err)
+	}
+	expected := "<methodCall><methodName>Some.Method</methodName><params><param><value><int>7</int></value></param><param><value><int>0</int></value></param></params></methodCall>"
+	if xml != expected {
+		t.Error("Expected", expected)
+		t.Error("Got", xml)
+	}
+
+	data := "<methodResponse><params><param><value><int>007</int></value></param><param><value><i4>-007</i4></value></param><param><value><int>007</int></value></param><param><value><i8>0042</i8></value></param></params></methodResponse>"
+	var reply struct {
+		A, B int
+		N    Number
+		I    interface{}
+	}
+	if err := NewDecoder(Lenient()).xml2RPC(data, &reply); err != nil {
+		t.Error("XML2RPC conversion failed", err)
+	}
+	if reply.A != 7 || reply.B != -7 || reply.N != "007" || reply.I != int64(42) {
+		t.Errorf("expected 7, -7, 007 and 42, but got %+v", reply)
+	}
+
+	data = "<methodResponse><params><param><value><int>007</int></value></param><param><value><i8>-0042</i8></value></param></params></methodResponse>"
+	var text struct {
+		N Number
+		S string
+	}
+	if err := NewDecoder(PreserveNumericText()).xml2RPC(data, &text); err != nil {
+		t.Error("XML2RPC conversion failed", err)
+	}
+	if text.N != "007" || text.S != "-0042" {
+		t.Errorf("expected the text 007 and -0042 as received, but got %+v", text)
+	}
+	if err := NewDecoder(Strict(), PreserveNumericText()).xml2RPC(data, &text); err == nil {
+		t.Error("expected error decoding <int>007</int> into a Number in strict mode, but got nil")
+	}
+
+	if err := NewDecoder(Strict()).xml2RPC(data, &reply); err == nil {
+		t.Error("expected error decoding <int>007</int> in strict mode, but got nil")
+	}
+	var untyped []interface{}
+	if err := NewDecoder(Strict()).xml2RPC(data, &untyped); err == nil {
+		t.Error("expected error decoding <int>007</int> in strict mode, but got nil")
+	}
+}
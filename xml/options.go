@@ -0,0 +1,303 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// Option configures the behaviour of a Decoder, an Encoder, a Codec or a
+// Client.
+type Option func(*options)
+
+// ErrTooLarge is returned when a document exceeds the WithMaxBytes limit.
+var ErrTooLarge = errors.New("xml: document too large")
+
+// DefaultMaxDepth is the default limit on how deeply values may be nested.
+const DefaultMaxDepth = 100
+
+type options struct {
+	validate   bool
+	lenient    bool
+	strict     bool
+	collect    bool
+	allowExtra bool
+	maxDepth   int
+	maxBytes   int64
+
+	garbageLimit int
+	envelope     []string
+
+	streamThreshold int64
+
+	trimUntyped     bool
+	caseInsensitive bool
+	preserveNumeric bool
+	nameMapper      func(string) string
+	location        *time.Location
+	epochTimes      bool
+
+	apacheExtensions bool
+	charset          string
+	invalidChars     InvalidCharPolicy
+	nilPolicy        NilPolicy
+	cdata            bool
+	homogeneous      bool
+
+	httpClient     *http.Client
+	expectContinue time.Duration
+	cache          bool
+	cacheStore     Cache
+	cacheTTL       time.Duration
+	cacheMethods   map[string]bool
+	reauth         *reauthConfig
+	faultMappers   []faultMapper
+	warn           func(error)
+	transformers   []ParamTransformer
+	interceptors   []ResponseInterceptor
+}
+
+// defaults are the options of the package functions, such as
+// DecodeClientResponse, set with SetDefaults.
+var defaults []Option
+
+// SetDefaults sets the options used by the package functions, such as
+// DecodeClientResponse and EncodeServerResponse, for applications with a
+// single configuration. Decoders, encoders, clients and codecs created
+// with options don't use them.
+//
+// SetDefaults is meant to be called at init time, as it isn't safe to call
+// concurrently with encoding or decoding.
+func SetDefaults(opts ...Option) {
+	defaults = opts
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		maxDepth:        DefaultMaxDepth,
+		streamThreshold: DefaultStreamThreshold,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// ValidateSyntax makes the decoder check that the document is well-formed
+// XML-RPC before converting it, so that malformed input is reported with
+// a precise *SyntaxError instead of a generic decoding Fault.
+func ValidateSyntax() Option {
+	return func(o *options) {
+		o.validate = true
+	}
+}
+
+// Lenient makes the decoder accept common deviations from the XML-RPC
+// specification, such as values nested in unexpected elements or integers
+// sent for floating point fields, instead of ignoring or rejecting them.
+// Booleans sent as strings or as the integers 0 and 1, element names in
+// any case, such as <Int>, and dateTime values in the extended ISO 8601
+// forms are accepted too, dateTime years out of the 1-9999 range are
+// clamped to it, arrays sent as a bare <data> element are decoded, single
+// values sent for slices are decoded as one item, and control characters
+// XML doesn't allow are skipped.
+func Lenient() Option {
+	return func(o *options) {
+		o.lenient = true
+	}
+}
+
+// CollectErrors makes the decoder carry on past the values it can't
+// decode, such as mismatched types or malformed numbers, and return all
+// the failures at once as a *DecodeErrors. Malformed numbers, which are
+// otherwise decoded as zero, are reported as well.
+func CollectErrors() Option {
+	return func(o *options) {
+		o.collect = true
+	}
+}
+
+// AllowExtraParams makes the decoder ignore the params of a response
+// beyond the fields of the reply, instead of failing. Each ignored param is
+// reported to the warning handler, if any.
+func AllowExtraParams() Option {
+	return func(o *options) {
+		o.allowExtra = true
+	}
+}
+
+// ApacheExtensions makes the encoder write int64 values as <ex:i8> and
+// nil values as <ex:nil/>, declaring the extensions namespace on the
+// document root. Without it, int64 values are written as <int>, or as
+// <i8> if they don't fit in 32 bits. The decoder accepts these types
+// whatever their prefix.
+func ApacheExtensions() Option {
+	return func(o *options) {
+		o.apacheExtensions = true
+	}
+}
+
+// WithCharset makes the encoder write documents in the single-byte charset
+// called name, such as "ISO-8859-1", instead of UTF-8. Characters the
+// charset lacks are written as numeric character references.
+func WithCharset(name string) Option {
+	return func(o *options) {
+		o.charset = name
+	}
+}
+
+// CDATAStrings makes the encoder write the strings containing markup
+// characters, such as < or &, as CDATA sections instead of escaping them.
+// Characters a charset set with WithCharset lacks can't be written in
+// CDATA sections, so the option should only be used with UTF-8.
+func CDATAStrings() Option {
+	return func(o *options) {
+		o.cdata = true
+	}
+}
+
+// HomogeneousArrays makes the encoder fail with an *EncodeError on arrays
+// whose items aren't all of the same XML-RPC type, such as a []interface{}
+// holding an int and a string, for servers which reject them. Nil items
+// are of their own type.
+func HomogeneousArrays() Option {
+	return func(o *options) {
+		o.homogeneous = true
+	}
+}
+
+// NilPolicy tells the encoder what to do with nil struct members.
+type NilPolicy int
+
+const (
+	// NilEmit writes nil pointers and interfaces as <nil/>, and nil slices
+	// and maps as empty arrays and structs, as for empty ones. This is
+	// the default.
+	NilEmit NilPolicy = iota
+	// NilOmit leaves out the struct and map members which are nil
+	// pointers, interfaces, slices or maps, or zero time.Time values.
+	// Parameters are never left out, as that would shift the ones after
+	// them.
+	NilOmit
+)
+
+// WithNilPolicy sets how the encoder handles nil struct members.
+func WithNilPolicy(policy NilPolicy) Option {
+	return func(o *options) {
+		o.nilPolicy = policy
+	}
+}
+
+// Strict makes the decoder reject deviations from the XML-RPC
+// specification it otherwise skips, such as values of unknown types, or
+// tolerates, such as integers with leading zeros or values of several
+// types, of which the first is otherwise decoded.
+func Strict() Option {
+	return func(o *options) {
+		o.strict = true
+	}
+}
+
+// WithWarningHandler makes the decoder report the problems it skips over,
+// such as values of unknown types, to handle. The warnings are
+// *DecodeError values holding the path of the value.
+func WithWarningHandler(handle func(err error)) Option {
+	return func(o *options) {
+		o.warn = handle
+	}
+}
+
+// TrimUntypedStrings makes the decoder trim the white space around the
+// text of untyped values, which are strings.
+func TrimUntypedStrings() Option {
+	return func(o *options) {
+		o.trimUntyped = true
+	}
+}
+
+// CaseInsensitiveFields makes the decoder match the members of structs to
+// the fields without a member of the exact name, ignoring case. Members
+// matching several fields are rejected.
+func CaseInsensitiveFields() Option {
+	return func(o *options) {
+		o.caseInsensitive = true
+	}
+}
+
+// WithNameMapper makes the decoder and the encoder name the members of
+// struct fields without a tag with mapper, which is passed the field
+// name. Tagged fields keep their name. See SnakeCase.
+func WithNameMapper(mapper func(field string) string) Option {
+	return func(o *options) {
+		o.nameMapper = mapper
+	}
+}
+
+// WithLocation sets the time zone of dateTime values without one: the
+// decoder interprets them in loc, instead of the local time zone, and the
+// encoder converts times to loc before writing them.
+func WithLocation(loc *time.Location) Option {
+	return func(o *options) {
+		o.location = loc
+	}
+}
+
+// EpochTimes makes the decoder accept integers for time.Time fields, as
+// numbers of seconds since January 1, 1970 UTC. The times are in the
+// location set with WithLocation, if any, and in the local time zone
+// otherwise.
+func EpochTimes() Option {
+	return func(o *options) {
+		o.epochTimes = true
+	}
+}
+
+// WithMaxDepth limits how deeply arrays and structs may be nested in a
+// validated document, and in the values an Encoder encodes. A limit of
+// zero disables the check.
+func WithMaxDepth(depth int) Option {
+	return func(o *options) {
+		o.maxDepth = depth
+	}
+}
+
+// WithMaxBytes limits the size of the documents which are read. A limit of
+// zero, the default, disables the check.
+func WithMaxBytes(n int64) Option {
+	return func(o *options) {
+		o.maxBytes = n
+	}
+}
+
+// readAll reads r up to the configured size limit. In lenient mode, the
+// characters XML doesn't allow are skipped.
+func (o *options) readAll(r io.Reader) ([]byte, error) {
+	data, err := o.readLimited(r)
+	if err == nil && o.lenient {
+		data = []byte(stripInvalidChars(string(data)))
+	}
+	return data, err
+}
+
+// readLimited reads r up to the configured size limit.
+func (o *options) readLimited(r io.Reader) ([]byte, error) {
+	var (
+		data []byte
+		err  error
+	)
+	if o.maxBytes <= 0 {
+		data, err = ioutil.ReadAll(r)
+	} else {
+		data, err = ioutil.ReadAll(io.LimitReader(r, o.maxBytes+1))
+		if err == nil && int64(len(data)) > o.maxBytes {
+			err = ErrTooLarge
+		}
+	}
+	return data, err
+}
@@ -0,0 +1,68 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+
+	"github.com/rogpeppe/go-charset/charset"
+)
+
+// PeekMethod reads the methodCall from r far enough to return the name of
+// the method and the number of params, without decoding the params, such
+// as to route calls. Reading stops at the end of the
+// params, so a copy of what is read, such as with io.TeeReader, is needed
+// to decode the call afterwards.
+func PeekMethod(r io.Reader) (method string, paramCount int, err error) {
+	return NewDecoder(defaults...).PeekMethod(r)
+}
+
+// PeekMethod is like the package function of the same name, reading with
+// the options of d.
+func (d *Decoder) PeekMethod(r io.Reader) (method string, paramCount int, err error) {
+	if d.opts.maxBytes > 0 {
+		r = &limitedReader{r: r, n: d.opts.maxBytes}
+	}
+	if d.opts.lenient {
+		r = &nameFilter{r: &controlCharFilter{r: r}}
+	}
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = charset.NewReader
+
+	var inParams bool
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return method, paramCount, nil
+		}
+		if err != nil {
+			return "", 0, syntaxError(decoder, "", err)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch {
+			case t.Name.Local == "methodName":
+				var name string
+				if err := decoder.DecodeElement(&name, &t); err != nil {
+					return "", 0, syntaxError(decoder, "", err)
+				}
+				method = strings.TrimSpace(name)
+			case t.Name.Local == "params":
+				inParams = true
+			case inParams && t.Name.Local == "param":
+				paramCount++
+				if err := decoder.Skip(); err != nil {
+					return "", 0, syntaxError(decoder, "", err)
+				}
+			}
+		case xml.EndElement:
+			if t.Name.Local == "params" {
+				return method, paramCount, nil
+			}
+		}
+	}
+}
@@ -0,0 +1,40 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestPeekMethod(t *testing.T) {
+	// The params aren't decoded, so that their values don't matter.
+	call := "<?xml version=\"1.0\"?><methodCall><methodName> Service1.Multiply </methodName><params>" +
+		"<param><value><int>not a number</int></value></param>" +
+		"<param><value><struct><member><name>params</name><value><array><data><value><i4>1</i4></value></data></array></value></member></struct></value></param>" +
+		"<param><value><unknown/></value></param>" +
+		"</params>"
+	// Reading stops at the end of the params.
+	r := io.MultiReader(strings.NewReader(call), iotest.ErrReader(errors.New("read past the params")))
+	method, n, err := PeekMethod(r)
+	if err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if method != "Service1.Multiply" || n != 3 {
+		t.Errorf("expected Service1.Multiply with 3 params, but got %s with %d", method, n)
+	}
+
+	method, n, err = PeekMethod(strings.NewReader("<methodCall><methodName>system.listMethods</methodName></methodCall>"))
+	if err != nil || method != "system.listMethods" || n != 0 {
+		t.Errorf("expected system.listMethods with no params, but got %s with %d, %v", method, n, err)
+	}
+
+	if _, _, err := PeekMethod(strings.NewReader("<methodCall><methodName>Broken</methodName><params><param>")); err == nil {
+		t.Error("expected error for a truncated call, but got nil")
+	}
+}
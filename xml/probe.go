@@ -0,0 +1,66 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"context"
+	"sort"
+)
+
+// Capabilities describes the features supported by an XML-RPC server, as
+// reported by Client.Probe.
+type Capabilities struct {
+	Introspection bool // system.listMethods and the like
+	Multicall     bool // system.multicall
+	Nil           bool // the <nil/> extension
+	Int8          bool // the <i8> extension
+
+	// Names lists the capabilities reported by system.getCapabilities,
+	// or is nil if the server doesn't implement it.
+	Names []string
+	// Methods lists the methods reported by system.listMethods, when the
+	// server doesn't implement system.getCapabilities.
+	Methods []string
+}
+
+// Probe asks the server which features it supports, calling
+// system.getCapabilities or, if the server doesn't implement it,
+// system.listMethods, which tells less.
+func (c *Client) Probe(ctx context.Context) (Capabilities, error) {
+	var caps Capabilities
+	var capabilities struct {
+		Capabilities map[string]interface{}
+	}
+	if err := c.Call(ctx, "system.getCapabilities", &struct{}{}, &capabilities); err == nil {
+		for name := range capabilities.Capabilities {
+			caps.Names = append(caps.Names, name)
+			switch name {
+			case "introspection":
+				caps.Introspection = true
+			case "system.multicall":
+				caps.Multicall = true
+			case "nil":
+				caps.Nil = true
+			case "i8", "ex:i8":
+				caps.Int8 = true
+			}
+		}
+		sort.Strings(caps.Names)
+		return caps, nil
+	}
+
+	var methods struct{ Methods []string }
+	if err := c.Call(ctx, "system.listMethods", &struct{}{}, &methods); err != nil {
+		return caps, err
+	}
+	caps.Introspection = true
+	caps.Methods = methods.Methods
+	for _, method := range methods.Methods {
+		if method == "system.multicall" {
+			caps.Multicall = true
+		}
+	}
+	return caps, nil
+}
@@ -0,0 +1,69 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// capability returns the member describing the capability called name.
+func capability(name, url string) string {
+	return "<member><name>" + name + "</name><value><struct><member><name>specUrl</name><value><string>" + url + "</string></value></member><member><name>specVersion</name><value><int>1</int></value></member></struct></value></member>"
+}
+
+func TestClientProbe(t *testing.T) {
+	capabilities := "<?xml version=\"1.0\"?><methodResponse><params><param><value><struct>" +
+		capability("xmlrpc", "http://www.xmlrpc.com/spec") +
+		capability("system.multicall", "http://www.xmlrpc.com/discuss/msgReader$1208") +
+		capability("introspection", "http://xmlrpc-c.sourceforge.net/introspection.html") +
+		capability("nil", "http://www.ontosys.com/xml-rpc/extensions.php") +
+		"</struct></value></param></params></methodResponse>"
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var args []interface{}
+		method, _ := DecodeServerRequest(r.Body, &args)
+		switch {
+		case method == "system.getCapabilities" && r.URL.Path == "/capabilities":
+			w.Write([]byte(capabilities))
+		case method == "system.listMethods":
+			buf, _ := EncodeServerResponse(&struct{ Methods []string }{[]string{"system.listMethods", "system.multicall", "Service1.Multiply"}})
+			w.Write(buf)
+		default:
+			w.Write(EncodeFault(FaultMethodNotFound))
+		}
+	}))
+	defer s.Close()
+
+	caps, err := NewClient(s.URL + "/capabilities").Probe(context.Background())
+	if err != nil {
+		t.Error("Expected err to be nil, but got:", err)
+	}
+	expected := Capabilities{
+		Introspection: true,
+		Multicall:     true,
+		Nil:           true,
+		Names:         []string{"introspection", "nil", "system.multicall", "xmlrpc"},
+	}
+	if !reflect.DeepEqual(caps, expected) {
+		t.Errorf("expected %+v, but got %+v", expected, caps)
+	}
+
+	caps, err = NewClient(s.URL + "/methods").Probe(context.Background())
+	if err != nil {
+		t.Error("Expected err to be nil, but got:", err)
+	}
+	expected = Capabilities{
+		Introspection: true,
+		Multicall:     true,
+		Methods:       []string{"system.listMethods", "system.multicall", "Service1.Multiply"},
+	}
+	if !reflect.DeepEqual(caps, expected) {
+		t.Errorf("expected %+v, but got %+v", expected, caps)
+	}
+}
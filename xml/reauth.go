@@ -0,0 +1,63 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"context"
+	"sync"
+)
+
+// WithReauth makes a Client log in again when a call fails with a fault
+// isExpired reports true for, such as an expired session, and then retry
+// the call once. A second such fault is returned. login is run once for
+// all the calls failing at the same time; its calls through c aren't
+// retried. To send a refreshed session token with every call, see
+// WithLeadingParamsFunc.
+func WithReauth(isExpired func(*Fault) bool, login func(ctx context.Context, c *Client) error) Option {
+	return func(o *options) {
+		o.reauth = &reauthConfig{isExpired: isExpired, login: login}
+	}
+}
+
+type reauthConfig struct {
+	isExpired func(*Fault) bool
+	login     func(ctx context.Context, c *Client) error
+}
+
+// reauth tracks the logins of a Client. generation counts the successful
+// logins, so that calls failing with a session older than the last login
+// are just retried.
+type reauth struct {
+	reauthConfig
+	mu         sync.Mutex
+	generation int
+}
+
+func (r *reauth) current() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.generation
+}
+
+// refresh logs in again, unless another call did since generation.
+func (r *reauth) refresh(ctx context.Context, c *Client, generation int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.generation != generation {
+		return nil
+	}
+	if err := r.login(context.WithValue(ctx, loginKey{}, true), c); err != nil {
+		return err
+	}
+	r.generation++
+	return nil
+}
+
+type loginKey struct{}
+
+// inLogin reports whether ctx is the one of a login.
+func inLogin(ctx context.Context) bool {
+	return ctx.Value(loginKey{}) != nil
+}
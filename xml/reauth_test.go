@@ -0,0 +1,158 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// sessionServer answers login calls with a new session token, and other
+// calls with the product of their parameters, or fault 403 if their
+// session isn't the current one. expire makes the current session expire.
+type sessionServer struct {
+	mu      sync.Mutex
+	session int
+	logins  int64
+}
+
+func (s *sessionServer) expire() {
+	s.mu.Lock()
+	s.session++
+	s.mu.Unlock()
+}
+
+func (s *sessionServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var args []interface{}
+	method, err := DecodeServerRequest(r.Body, &args)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	session := strconv.Itoa(s.session)
+	s.mu.Unlock()
+	if method == "login" {
+		atomic.AddInt64(&s.logins, 1)
+		buf, _ := EncodeServerResponse(&struct{ Token string }{session})
+		w.Write(buf)
+		return
+	}
+	if len(args) != 3 || args[0] != session {
+		w.Write(EncodeFault(Fault{Code: 403, String: "session expired"}))
+		return
+	}
+	buf, _ := EncodeServerResponse(&Service1Response{args[1].(int) * args[2].(int)})
+	w.Write(buf)
+}
+
+func newSessionClient(url string) *Client {
+	var (
+		mu    sync.Mutex
+		token = "none"
+	)
+	return NewClient(url,
+		WithLeadingParamsFunc(func() []interface{} {
+			mu.Lock()
+			defer mu.Unlock()
+			return []interface{}{token}
+		}),
+		WithReauth(
+			func(f *Fault) bool { return f.Code == 403 },
+			func(ctx context.Context, c *Client) error {
+				var reply struct{ Token string }
+				if err := c.Call(ctx, "login", &struct{}{}, &reply); err != nil {
+					return err
+				}
+				mu.Lock()
+				token = reply.Token
+				mu.Unlock()
+				return nil
+			},
+		),
+	)
+}
+
+func TestClientReauth(t *testing.T) {
+	server := &sessionServer{}
+	s := httptest.NewServer(server)
+	defer s.Close()
+	c := newSessionClient(s.URL)
+
+	multiply := func() error {
+		var res Service1Response
+		err := c.Call(context.Background(), "Multiply", &Service1Request{4, 2}, &res)
+		if err == nil && res.Result != 8 {
+			t.Errorf("Wrong response: %v.", res.Result)
+		}
+		return err
+	}
+
+	// The first call logs in.
+	if err := multiply(); err != nil {
+		t.Error("Expected err to be nil, but got:", err)
+	}
+	if err := multiply(); err != nil {
+		t.Error("Expected err to be nil, but got:", err)
+	}
+	if n := atomic.LoadInt64(&server.logins); n != 1 {
+		t.Errorf("expected 1 login, but got %d", n)
+	}
+	// The call retried after the login is counted once, as a success.
+	if stats := c.Stats(); stats.Successes != 3 || len(stats.Faults) != 0 {
+		t.Errorf("expected 3 successes, with the login, and no faults, but got %+v", stats)
+	}
+
+	// Concurrent calls failing together log in once.
+	server.expire()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := multiply(); err != nil {
+				t.Error("Expected err to be nil, but got:", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := atomic.LoadInt64(&server.logins); n != 2 {
+		t.Errorf("expected 2 logins, but got %d", n)
+	}
+}
+
+func TestClientReauthOnce(t *testing.T) {
+	var logins int64
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var args []interface{}
+		if method, _ := DecodeServerRequest(r.Body, &args); method == "login" {
+			atomic.AddInt64(&logins, 1)
+			buf, _ := EncodeServerResponse(&struct{ Token string }{"token"})
+			w.Write(buf)
+			return
+		}
+		w.Write(EncodeFault(Fault{Code: 403, String: "session expired"}))
+	}))
+	defer s.Close()
+	c := newSessionClient(s.URL)
+
+	var res Service1Response
+	err := c.Call(context.Background(), "Multiply", &Service1Request{4, 2}, &res)
+	if fault, ok := err.(Fault); !ok || fault.Code != 403 {
+		t.Errorf("expected fault 403, but got %v", err)
+	}
+	if n := atomic.LoadInt64(&logins); n != 1 {
+		t.Errorf("expected 1 login, but got %d", n)
+	}
+	if stats := c.Stats(); stats.Successes != 1 || !reflect.DeepEqual(stats.Faults, map[int]int64{403: 1}) {
+		t.Errorf("expected the login as a success and the call as fault 403, but got %+v", stats)
+	}
+}
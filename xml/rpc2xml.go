@@ -7,74 +7,153 @@ package xml
 import (
 	"encoding/base64"
 	"fmt"
+	"math"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
 
 func rpcRequest2XML(method string, rpc interface{}) (string, error) {
-	buffer := "<methodCall><methodName>"
+	return NewEncoder(defaults...).rpcRequest2XML(method, rpc)
+}
+
+func rpcResponse2XML(rpc interface{}) (string, error) {
+	return NewEncoder(defaults...).rpcResponse2XML(rpc)
+}
+
+// root returns the start tag of the document root element, declaring the
+// extensions namespace when it may be used.
+func (e *Encoder) root(name string) string {
+	if e.opts.apacheExtensions {
+		return fmt.Sprintf(`<%s xmlns:ex="%s">`, name, ExtensionsNamespace)
+	}
+	return "<" + name + ">"
+}
+
+func (e *Encoder) rpcRequest2XML(method string, rpc interface{}) (string, error) {
+	buffer := e.root("methodCall")
+	buffer += "<methodName>"
 	buffer += method
 	buffer += "</methodName>"
-	params, err := rpcParams2XML(rpc)
+	params, err := e.rpcParams2XML(rpc)
 	buffer += params
 	buffer += "</methodCall>"
 	return buffer, err
 }
 
-func rpcResponse2XML(rpc interface{}) (string, error) {
-	buffer := "<methodResponse>"
-	params, err := rpcParams2XML(rpc)
+func (e *Encoder) rpcResponse2XML(rpc interface{}) (string, error) {
+	buffer := e.root("methodResponse")
+	params, err := e.rpcParams2XML(rpc)
 	buffer += params
 	buffer += "</methodResponse>"
 	return buffer, err
 }
 
-func rpcParams2XML(rpc interface{}) (string, error) {
+func (e *Encoder) rpcParams2XML(rpc interface{}) (string, error) {
 	var err error
 	buffer := "<params>"
+	raw := rawXMLIndex(reflect.ValueOf(rpc).Elem().Type())
+	// n is the position of the param, which differs from the field index
+	// after a rawxml field.
+	n := 0
 	for i := 0; i < reflect.ValueOf(rpc).Elem().NumField(); i++ {
+		if i == raw {
+			continue
+		}
 		var xml string
 		buffer += "<param>"
-		xml, err = rpc2XML(reflect.ValueOf(rpc).Elem().Field(i).Interface())
+		field := reflect.ValueOf(rpc).Elem().Field(i)
+		_, opts := parseTag(reflect.ValueOf(rpc).Elem().Type().Field(i).Tag.Get("xmlrpc"))
+		if layout, ok := opts["layout"]; ok && field.Type() == timeType {
+			xml = "<value>" + time2Layout(field.Interface().(time.Time), layout, fieldInfo{opts: opts}) + "</value>"
+		} else {
+			xml, err = e.rpc2XML(field.Interface())
+		}
 		buffer += xml
 		buffer += "</param>"
+		if err != nil {
+			return buffer, encodeError("params["+strconv.Itoa(n)+"]", err)
+		}
+		n++
 	}
 	buffer += "</params>"
 	return buffer, err
 }
 
-func rpc2XML(value interface{}) (string, error) {
+func (e *Encoder) rpc2XML(value interface{}) (string, error) {
+	var (
+		xml string
+		err error
+	)
 	out := "<value>"
-	switch reflect.ValueOf(value).Kind() {
+	// Values are read by kind, as they may be of named types, such as
+	// time.Duration.
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
 	case reflect.Int:
-		out += fmt.Sprintf("<int>%d</int>", value.(int))
+		out += fmt.Sprintf("<int>%d</int>", v.Int())
+	case reflect.Int64:
+		switch n := v.Int(); {
+		case e.opts.apacheExtensions:
+			out += fmt.Sprintf("<ex:i8>%d</ex:i8>", n)
+		case n < math.MinInt32 || n > math.MaxInt32:
+			// <int> is 32-bit; <i8> is understood by most servers.
+			out += fmt.Sprintf("<i8>%d</i8>", n)
+		default:
+			out += fmt.Sprintf("<int>%d</int>", n)
+		}
 	case reflect.Float64:
-		out += fmt.Sprintf("<double>%f</double>", value.(float64))
+		out += fmt.Sprintf("<double>%f</double>", v.Float())
 	case reflect.String:
-		out += string2XML(value.(string))
+		if n, ok := value.(Number); ok {
+			out += number2XML(n)
+			break
+		}
+		xml, err = e.string2XML(v.String())
+		out += xml
 	case reflect.Bool:
-		out += bool2XML(value.(bool))
+		out += bool2XML(v.Bool())
 	case reflect.Struct:
 		if reflect.TypeOf(value).String() != "time.Time" {
-			out += struct2XML(value)
+			xml, err = e.struct2XML(value)
+			out += xml
 		} else {
-			out += time2XML(value.(time.Time))
+			t := value.(time.Time)
+			if e.opts.location != nil {
+				t = t.In(e.opts.location)
+			}
+			xml, err = time2XML(t)
+			out += xml
 		}
+	case reflect.Map:
+		xml, err = e.map2XML(value)
+		out += xml
 	case reflect.Slice, reflect.Array:
 		// FIXME: is it the best way to recognize '[]byte'?
 		if reflect.TypeOf(value).String() != "[]uint8" {
-			out += array2XML(value)
+			xml, err = e.array2XML(value)
+			out += xml
 		} else {
 			out += base642XML(value.([]byte))
 		}
 	case reflect.Ptr:
 		if reflect.ValueOf(value).IsNil() {
-			out += "<nil/>"
+			out += e.nil2XML()
 		}
+	case reflect.Invalid:
+		out += e.nil2XML()
 	}
 	out += "</value>"
-	return out, nil
+	return out, err
+}
+
+func (e *Encoder) nil2XML() string {
+	if e.opts.apacheExtensions {
+		return "<ex:nil/>"
+	}
+	return "<nil/>"
 }
 
 func bool2XML(value bool) string {
@@ -87,44 +166,185 @@ func bool2XML(value bool) string {
 	return fmt.Sprintf("<boolean>%s</boolean>", b)
 }
 
-func string2XML(value string) string {
+func (e *Encoder) string2XML(value string) (string, error) {
+	value, err := e.opts.invalidChars.sanitize(value)
+	if e.opts.cdata && strings.ContainsAny(value, "<>&") {
+		return "<string>" + cdata(value) + "</string>", err
+	}
+	return fmt.Sprintf("<string>%s</string>", escapeString(value)), err
+}
+
+// cdata writes value as a CDATA section, split where value contains the
+// end of a section, "]]>".
+func cdata(value string) string {
+	return "<![CDATA[" + strings.Replace(value, "]]>", "]]]]><![CDATA[>", -1) + "]]>"
+}
+
+func escapeString(value string) string {
 	value = strings.Replace(value, "&", "&amp;", -1)
 	value = strings.Replace(value, "\"", "&quot;", -1)
 	value = strings.Replace(value, "<", "&lt;", -1)
 	value = strings.Replace(value, ">", "&gt;", -1)
-	return fmt.Sprintf("<string>%s</string>", value)
+	return value
 }
 
-func struct2XML(value interface{}) (out string) {
-	out += "<struct>"
-	for i := 0; i < reflect.TypeOf(value).NumField(); i++ {
-		field := reflect.ValueOf(value).Field(i)
-		field_type := reflect.TypeOf(value).Field(i)
-		var name string
-		if field_type.Tag.Get("xml") != "" {
-			name = field_type.Tag.Get("xml")
-		} else {
-			name = field_type.Name
+// nested returns a copy of e to encode the members of a struct, or the
+// items of an array, with. It fails past the maximum depth.
+func (e *Encoder) nested() (*Encoder, error) {
+	if e.opts.maxDepth > 0 && e.depth >= e.opts.maxDepth {
+		return nil, fmt.Errorf("values nested deeper than %d levels", e.opts.maxDepth)
+	}
+	return &Encoder{opts: e.opts, depth: e.depth + 1}, nil
+}
+
+func (e *Encoder) struct2XML(value interface{}) (out string, err error) {
+	if e, err = e.nested(); err != nil {
+		return "", err
+	}
+	v := reflect.ValueOf(value)
+	members, err := e.members2XML(v, e.opts.structFields(v.Type()), nil)
+	return "<struct>" + members + "</struct>", err
+}
+
+// members2XML encodes the fields of v, a struct, as members, promoting
+// those of embedded structs. Members named in hidden are left out, as
+// they are hidden by those of a parent.
+func (e *Encoder) members2XML(v reflect.Value, fields fieldList, hidden map[string]bool) (out string, err error) {
+	names := make(map[string]bool, len(hidden)+len(fields))
+	for name := range hidden {
+		names[name] = true
+	}
+	for _, info := range fields {
+		if info.embedded == nil {
+			names[info.name] = true
 		}
-		field_value, _ := rpc2XML(field.Interface())
-		field_name := fmt.Sprintf("<name>%s</name>", name)
+	}
+	for _, info := range fields {
+		field := v.Field(info.index)
+		if info.embedded != nil {
+			if field.Kind() == reflect.Ptr {
+				if field.IsNil() {
+					continue
+				}
+				field = field.Elem()
+			}
+			members, err := e.members2XML(field, info.embedded, names)
+			out += members
+			if err != nil {
+				return out, err
+			}
+			continue
+		}
+		if hidden[info.name] || e.omit(field) || omitZeroTime(field, info) {
+			continue
+		}
+		var field_value string
+		if layout, ok := info.opts["layout"]; ok && field.Type() == timeType {
+			field_value = "<value>" + time2Layout(field.Interface().(time.Time), layout, info) + "</value>"
+		} else if field_value, err = e.rpc2XML(field.Interface()); err != nil {
+			return out, encodeError("."+info.name, err)
+		}
+		field_name := fmt.Sprintf("<name>%s</name>", info.name)
 		out += fmt.Sprintf("<member>%s%s</member>", field_name, field_value)
 	}
+	return
+}
+
+// map2XML encodes a map as a struct, with members sorted by name.
+func (e *Encoder) map2XML(value interface{}) (out string, err error) {
+	if e, err = e.nested(); err != nil {
+		return "", err
+	}
+	v := reflect.ValueOf(value)
+	names := make([]string, 0, v.Len())
+	keys := make(map[string]reflect.Value, v.Len())
+	for _, key := range v.MapKeys() {
+		name := fmt.Sprint(key.Interface())
+		names = append(names, name)
+		keys[name] = key
+	}
+	sort.Strings(names)
+
+	out += "<struct>"
+	for _, name := range names {
+		if e.omit(v.MapIndex(keys[name])) {
+			continue
+		}
+		field_value, err := e.rpc2XML(v.MapIndex(keys[name]).Interface())
+		if err != nil {
+			return out, encodeError("."+name, err)
+		}
+		out += fmt.Sprintf("<member><name>%s</name>%s</member>", escapeString(name), field_value)
+	}
 	out += "</struct>"
 	return
 }
 
-func array2XML(value interface{}) (out string) {
+// omit reports whether the member v is left out of structs.
+func (e *Encoder) omit(v reflect.Value) bool {
+	if e.opts.nilPolicy != NilOmit {
+		return false
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+		return v.IsNil()
+	case reflect.Struct:
+		return v.Type() == timeType && v.Interface().(time.Time).IsZero()
+	}
+	return false
+}
+
+// omitZeroTime reports whether field, the time.Time field described by
+// info, is left out of structs as it is zero and tagged omitempty. Fields
+// with a layout are written as an empty string instead.
+func omitZeroTime(field reflect.Value, info fieldInfo) bool {
+	if field.Type() != timeType || !field.Interface().(time.Time).IsZero() {
+		return false
+	}
+	_, omitempty := info.opts["omitempty"]
+	_, layout := info.opts["layout"]
+	return omitempty && !layout
+}
+
+func (e *Encoder) array2XML(value interface{}) (out string, err error) {
+	if e, err = e.nested(); err != nil {
+		return "", err
+	}
 	out += "<array><data>"
+	var first string
 	for i := 0; i < reflect.ValueOf(value).Len(); i++ {
-		item_xml, _ := rpc2XML(reflect.ValueOf(value).Index(i).Interface())
+		item_xml, err := e.rpc2XML(reflect.ValueOf(value).Index(i).Interface())
+		if err != nil {
+			return out, encodeError("["+strconv.Itoa(i)+"]", err)
+		}
+		if e.opts.homogeneous {
+			if kind := valueType(item_xml); i == 0 {
+				first = kind
+			} else if kind != first {
+				return out, encodeError("["+strconv.Itoa(i)+"]", fmt.Errorf("array item of type %s after items of type %s", kind, first))
+			}
+		}
 		out += item_xml
 	}
 	out += "</data></array>"
 	return
 }
 
-func time2XML(t time.Time) string {
+// valueType returns the name of the type element of xml, an encoded
+// <value>, such as "int".
+func valueType(xml string) string {
+	xml = strings.TrimPrefix(xml, "<value><")
+	if i := strings.IndexAny(xml, "/>"); i >= 0 {
+		return xml[:i]
+	}
+	return ""
+}
+
+// time2XML encodes t, failing if its year doesn't have four digits.
+func time2XML(t time.Time) (string, error) {
+	if year := t.Year(); year < minYear || year > maxYear {
+		return "", fmt.Errorf("time %v: year out of range %d-%d", t, minYear, maxYear)
+	}
 	/*
 		// TODO: find out whether we need to deal
 		// here with TZ
@@ -138,7 +358,16 @@ func time2XML(t time.Time) string {
 	*/
 	return fmt.Sprintf("<dateTime.iso8601>%04d%02d%02dT%02d:%02d:%02d</dateTime.iso8601>",
 		t.Year(), t.Month(), t.Day(),
-		t.Hour(), t.Minute(), t.Second())
+		t.Hour(), t.Minute(), t.Second()), nil
+}
+
+// time2Layout encodes t as a string formatted with layout, or an empty
+// string for the zero time of fields tagged omitempty.
+func time2Layout(t time.Time, layout string, info fieldInfo) string {
+	if _, omitempty := info.opts["omitempty"]; omitempty && t.IsZero() {
+		return "<string></string>"
+	}
+	return fmt.Sprintf("<string>%s</string>", escapeString(t.Format(layout)))
 }
 
 func base642XML(data []byte) string {
@@ -5,6 +5,8 @@
 package xml
 
 import (
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -74,3 +76,262 @@ func TestRpc2XmlNil(t *testing.T) {
 		t.Error("Got", xml)
 	}
 }
+
+type StructNilSlices struct {
+	Name   string
+	Nil    []int
+	Empty  []int
+	Values []int
+}
+
+func TestRPC2XMLNilPolicy(t *testing.T) {
+	req := &struct{ S StructNilSlices }{StructNilSlices{"slices", nil, []int{}, []int{1}}}
+	tests := []struct {
+		policy   NilPolicy
+		expected string
+	}{
+		{NilEmit, "<methodResponse><params><param><value><struct><member><name>Name</name><value><string>slices</string></value></member><member><name>Nil</name><value><array><data></data></array></value></member><member><name>Empty</name><value><array><data></data></array></value></member><member><name>Values</name><value><array><data><value><int>1</int></value></data></array></value></member></struct></value></param></params></methodResponse>"},
+		{NilOmit, "<methodResponse><params><param><value><struct><member><name>Name</name><value><string>slices</string></value></member><member><name>Empty</name><value><array><data></data></array></value></member><member><name>Values</name><value><array><data><value><int>1</int></value></data></array></value></member></struct></value></param></params></methodResponse>"},
+	}
+	for _, test := range tests {
+		xml, err := NewEncoder(WithNilPolicy(test.policy)).rpcResponse2XML(req)
+		if err != nil {
+			t.Error("RPC2XML conversion failed", err)
+		}
+		if xml != test.expected {
+			t.Error("RPC2XML conversion failed")
+			t.Error("Expected", test.expected)
+			t.Error("Got", xml)
+		}
+	}
+}
+
+func TestCDATAStrings(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"plain", "<string>plain</string>"},
+		{"<b>bold</b> & more", "<string><![CDATA[<b>bold</b> & more]]></string>"},
+		{"a]]>b", "<string><![CDATA[a]]]]><![CDATA[>b]]></string>"},
+		{"]]>]]>", "<string><![CDATA[]]]]><![CDATA[>]]]]><![CDATA[>]]></string>"},
+	}
+	e := NewEncoder(CDATAStrings())
+	for _, test := range tests {
+		xml, err := e.rpcRequest2XML("Some.Method", &struct{ S string }{test.value})
+		if err != nil {
+			t.Error("RPC2XML conversion failed", err)
+		}
+		expected := "<methodCall><methodName>Some.Method</methodName><params><param><value>" + test.expected + "</value></param></params></methodCall>"
+		if xml != expected {
+			t.Error("RPC2XML conversion failed")
+			t.Error("Expected", expected)
+			t.Error("Got", xml)
+		}
+
+		var req struct{ S string }
+		if _, err := DecodeServerRequest(strings.NewReader(xml), &req); err != nil {
+			t.Error("Expected err to be nil, but got:", err)
+		}
+		if req.S != test.value {
+			t.Errorf("expected %q, but got %q", test.value, req.S)
+		}
+	}
+}
+
+type EmbeddedBase struct {
+	ID      int    `xmlrpc:"id"`
+	Created string `xmlrpc:"created"`
+}
+
+type EmbeddedOwner struct {
+	Owner string `xmlrpc:"owner"`
+}
+
+type embeddedAudit struct {
+	Author string
+}
+
+type StructEmbedding struct {
+	EmbeddedBase
+	*EmbeddedOwner
+	embeddedAudit
+	Tagged  EmbeddedBase `xmlrpc:"tagged"`
+	Created string       `xmlrpc:"created"`
+}
+
+func TestRPC2XMLEmbedded(t *testing.T) {
+	req := &struct{ S StructEmbedding }{StructEmbedding{
+		EmbeddedBase:  EmbeddedBase{ID: 1, Created: "hidden"},
+		embeddedAudit: embeddedAudit{Author: "divan"},
+		Tagged:        EmbeddedBase{ID: 2, Created: "tagged"},
+		Created:       "today",
+	}}
+	xml, err := rpcRequest2XML("Some.Method", req)
+	if err != nil {
+		t.Error("RPC2XML conversion failed", err)
+	}
+	expected := "<methodCall><methodName>Some.Method</methodName><params><param><value><struct><member><name>id</name><value><int>1</int></value></member><member><name>Author</name><value><string>divan</string></value></member><member><name>tagged</name><value><struct><member><name>id</name><value><int>2</int></value></member><member><name>created</name><value><string>tagged</string></value></member></struct></value></member><member><name>created</name><value><string>today</string></value></member></struct></value></param></params></methodCall>"
+	if xml != expected {
+		t.Error("RPC2XML conversion failed")
+		t.Error("Expected", expected)
+		t.Error("Got", xml)
+	}
+
+	req.S.EmbeddedOwner = &EmbeddedOwner{Owner: "ivan"}
+	xml, err = rpcRequest2XML("Some.Method", req)
+	if err != nil {
+		t.Error("RPC2XML conversion failed", err)
+	}
+	if !strings.Contains(xml, "<member><name>id</name><value><int>1</int></value></member><member><name>owner</name><value><string>ivan</string></value></member>") {
+		t.Error("expected the members of an embedded pointer, but got", xml)
+	}
+
+	var decoded struct{ S StructEmbedding }
+	if _, err := DecodeServerRequest(strings.NewReader(xml), &decoded); err != nil {
+		t.Error("Expected err to be nil, but got:", err)
+	}
+	req.S.EmbeddedBase.Created = ""
+	if !reflect.DeepEqual(decoded, *req) {
+		t.Errorf("expected %+v, but got %+v", *req, decoded)
+	}
+}
+
+func TestRPC2XMLMaxDepth(t *testing.T) {
+	var nested interface{} = []interface{}{1}
+	for i := 0; i < 4; i++ {
+		nested = []interface{}{nested}
+	}
+	e := NewEncoder(WithMaxDepth(5))
+	if _, err := e.rpcRequest2XML("Some.Method", &struct{ A interface{} }{nested}); err != nil {
+		t.Error("Expected err to be nil, but got:", err)
+	}
+
+	nested = map[string]interface{}{"deeper": nested}
+	_, err := e.rpcRequest2XML("Some.Method", &struct{ A interface{} }{nested})
+	encodeErr, ok := err.(*EncodeError)
+	if !ok {
+		t.Fatalf("expected *EncodeError, but got %v", err)
+	}
+	if encodeErr.Path != "params[0].deeper[0][0][0][0]" {
+		t.Errorf("expected the path of the too deep array, but got %q", encodeErr.Path)
+	}
+
+	if _, err := NewEncoder(WithMaxDepth(0)).rpcRequest2XML("Some.Method", &struct{ A interface{} }{nested}); err != nil {
+		t.Error("Expected err to be nil without a limit, but got:", err)
+	}
+}
+
+func TestRPC2XMLHomogeneousArrays(t *testing.T) {
+	e := NewEncoder(HomogeneousArrays())
+	tests := []struct {
+		value interface{}
+		path  string
+	}{
+		{[]int{1, 2, 3}, ""},
+		{[]interface{}{1, 2.5}, "params[0][1]"},
+		{[]interface{}{"a", nil}, "params[0][1]"},
+		{[]interface{}{[]interface{}{1}, []interface{}{"a", 1}}, "params[0][1][1]"},
+		{[]interface{}{map[string]interface{}{"a": 1}, map[string]interface{}{"b": "c"}}, ""},
+		{[]interface{}{}, ""},
+	}
+	for _, test := range tests {
+		_, err := e.rpcRequest2XML("Some.Method", &struct{ A interface{} }{test.value})
+		if test.path == "" {
+			if err != nil {
+				t.Errorf("%v: Expected err to be nil, but got: %v", test.value, err)
+			}
+			continue
+		}
+		encodeErr, ok := err.(*EncodeError)
+		if !ok {
+			t.Errorf("%v: expected *EncodeError, but got %v", test.value, err)
+			continue
+		}
+		if encodeErr.Path != test.path {
+			t.Errorf("%v: expected path %q, but got %q", test.value, test.path, encodeErr.Path)
+		}
+	}
+
+	if _, err := rpcRequest2XML("Some.Method", &struct{ A interface{} }{[]interface{}{1, "a"}}); err != nil {
+		t.Error("Expected err to be nil without the option, but got:", err)
+	}
+}
+
+type StructZeroTimes struct {
+	Name     string
+	Created  time.Time
+	Modified time.Time `xmlrpc:"modified,omitempty"`
+}
+
+func TestRPC2XMLZeroTime(t *testing.T) {
+	req := &struct{ S StructZeroTimes }{StructZeroTimes{Name: "zero"}}
+	tests := []struct {
+		policy   NilPolicy
+		expected string
+	}{
+		{NilEmit, "<methodResponse><params><param><value><struct><member><name>Name</name><value><string>zero</string></value></member><member><name>Created</name><value><dateTime.iso8601>00010101T00:00:00</dateTime.iso8601></value></member></struct></value></param></params></methodResponse>"},
+		{NilOmit, "<methodResponse><params><param><value><struct><member><name>Name</name><value><string>zero</string></value></member></struct></value></param></params></methodResponse>"},
+	}
+	for _, test := range tests {
+		xml, err := NewEncoder(WithNilPolicy(test.policy)).rpcResponse2XML(req)
+		if err != nil {
+			t.Error("RPC2XML conversion failed", err)
+		}
+		if xml != test.expected {
+			t.Error("RPC2XML conversion failed")
+			t.Error("Expected", test.expected)
+			t.Error("Got", xml)
+		}
+	}
+
+	req.S.Modified = time.Date(2012, time.July, 17, 14, 8, 55, 0, time.UTC)
+	xml, err := NewEncoder(WithLocation(time.UTC)).rpcResponse2XML(req)
+	if err != nil {
+		t.Error("RPC2XML conversion failed", err)
+	}
+	if !strings.Contains(xml, "<member><name>modified</name><value><dateTime.iso8601>20120717T14:08:55</dateTime.iso8601></value></member>") {
+		t.Error("expected the non-zero time tagged omitempty, but got", xml)
+	}
+}
+
+type RecordID int64
+
+func TestRPC2XMLNamedInt64(t *testing.T) {
+	req := &struct {
+		Timeout time.Duration
+		ID      RecordID
+	}{90 * time.Second, 42}
+	xml, err := rpcRequest2XML("Some.Method", req)
+	if err != nil {
+		t.Error("RPC2XML conversion failed", err)
+	}
+	// Values out of the range of <int> are written as <i8>.
+	expected := "<methodCall><methodName>Some.Method</methodName><params><param><value><i8>90000000000</i8></value></param><param><value><int>42</int></value></param></params></methodCall>"
+	if xml != expected {
+		t.Error("RPC2XML conversion failed")
+		t.Error("Expected", expected)
+		t.Error("Got", xml)
+	}
+
+	req.Timeout = time.Millisecond
+	xml, err = rpcRequest2XML("Some.Method", req)
+	if err != nil {
+		t.Error("RPC2XML conversion failed", err)
+	}
+	expected = "<methodCall><methodName>Some.Method</methodName><params><param><value><int>1000000</int></value></param><param><value><int>42</int></value></param></params></methodCall>"
+	if xml != expected {
+		t.Error("RPC2XML conversion failed")
+		t.Error("Expected", expected)
+		t.Error("Got", xml)
+	}
+
+	req.Timeout = 90 * time.Second
+	xml, err = NewEncoder(ApacheExtensions()).rpcRequest2XML("Some.Method", req)
+	if err != nil {
+		t.Error("RPC2XML conversion failed", err)
+	}
+	if !strings.Contains(xml, "<param><value><ex:i8>90000000000</ex:i8></value></param><param><value><ex:i8>42</ex:i8></value></param>") {
+		t.Error("expected <ex:i8> values, but got", xml)
+	}
+}
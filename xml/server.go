@@ -5,12 +5,14 @@
 package xml
 
 import (
+	"bytes"
 	"encoding/xml"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"net/http"
 
 	"github.com/gorilla/rpc"
+	"github.com/rogpeppe/go-charset/charset"
 )
 
 // ----------------------------------------------------------------------------
@@ -18,15 +20,19 @@ import (
 // ----------------------------------------------------------------------------
 
 // NewCodec returns a new XML-RPC Codec.
-func NewCodec() *Codec {
+func NewCodec(opts ...Option) *Codec {
 	return &Codec{
 		aliases: make(map[string]string),
+		decoder: NewDecoder(opts...),
+		encoder: NewEncoder(opts...),
 	}
 }
 
 // Codec creates a CodecRequest to process each request.
 type Codec struct {
 	aliases map[string]string
+	decoder *Decoder
+	encoder *Encoder
 }
 
 // RegisterAlias creates a method alias
@@ -36,21 +42,28 @@ func (c *Codec) RegisterAlias(alias, method string) {
 
 // NewRequest returns a CodecRequest.
 func (c *Codec) NewRequest(r *http.Request) rpc.CodecRequest {
-	rawxml, err := ioutil.ReadAll(r.Body)
+	// Reject requests known to be too large before reading them, so that
+	// clients expecting a 100 Continue response don't send the body.
+	if max := c.decoder.opts.maxBytes; max > 0 && r.ContentLength > max {
+		return &CodecRequest{err: ErrTooLarge}
+	}
+	rawxml, err := c.decoder.opts.readAll(r.Body)
 	if err != nil {
 		return &CodecRequest{err: err}
 	}
 	defer r.Body.Close()
 
 	var request ServerRequest
-	if err := xml.Unmarshal(rawxml, &request); err != nil {
-		return &CodecRequest{err: err}
+	decoder := xml.NewDecoder(bytes.NewReader(rawxml))
+	decoder.CharsetReader = charset.NewReader
+	if err := decoder.Decode(&request); err != nil {
+		return &CodecRequest{err: syntaxError(decoder, string(rawxml), err)}
 	}
 	request.rawxml = string(rawxml)
 	if method, ok := c.aliases[request.Method]; ok {
 		request.Method = method
 	}
-	return &CodecRequest{request: &request}
+	return &CodecRequest{request: &request, decoder: c.decoder, encoder: c.encoder}
 }
 
 // ----------------------------------------------------------------------------
@@ -66,6 +79,8 @@ type ServerRequest struct {
 // CodecRequest decodes and encodes a single request.
 type CodecRequest struct {
 	request *ServerRequest
+	decoder *Decoder
+	encoder *Encoder
 	err     error
 }
 
@@ -84,7 +99,7 @@ func (c *CodecRequest) Method() (string, error) {
 // args is the pointer to the Service.Args structure
 // it gets populated from temporary XML structure
 func (c *CodecRequest) ReadRequest(args interface{}) error {
-	c.err = xml2RPC(c.request.rawxml, args)
+	c.err = c.decoder.xml2RPC(c.request.rawxml, args)
 	return nil
 }
 
@@ -94,21 +109,85 @@ func (c *CodecRequest) ReadRequest(args interface{}) error {
 // it gets encoded into the XML-RPC xml string
 func (c *CodecRequest) WriteResponse(w http.ResponseWriter, response interface{}, methodErr error) error {
 	var xmlstr string
+	if c.err == nil {
+		c.err = methodErr
+	}
 	if c.err != nil {
-		var fault Fault
-		switch c.err.(type) {
-		case Fault:
-			fault = c.err.(Fault)
-		default:
-			fault = FaultApplicationError
-			fault.String += fmt.Sprintf(": %v", c.err)
-		}
-		xmlstr = fault2XML(fault)
+		xmlstr = c.encoder.fault2XML(errorFault(c.err))
 	} else {
-		xmlstr, _ = rpcResponse2XML(response)
+		xmlstr, _ = c.encoder.rpcResponse2XML(response)
+	}
+	if len(c.encoder.opts.interceptors) != 0 {
+		var method string
+		if c.request != nil {
+			method = c.request.Method
+		}
+		xmlstr = c.encoder.interceptResponse(method, xmlstr)
 	}
 
-	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
-	w.Write([]byte(xmlstr))
+	data, err := c.encoder.document(xmlstr)
+	if err != nil {
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		fault := FaultInternalError
+		fault.String += fmt.Sprintf(": %v", err)
+		w.Write([]byte(fault2XML(fault)))
+		return err
+	}
+	w.Header().Set("Content-Type", c.encoder.contentType())
+	w.Write(data)
 	return nil
 }
+
+// errorFault returns the fault written in answer to err.
+func errorFault(err error) Fault {
+	var fault Fault
+	switch err.(type) {
+	case Fault:
+		fault = err.(Fault)
+	case *SyntaxError:
+		fault = FaultDecode
+		fault.String += fmt.Sprintf(": %v", err)
+	default:
+		fault = FaultApplicationError
+		fault.String += fmt.Sprintf(": %v", err)
+	}
+	return fault
+}
+
+// ----------------------------------------------------------------------------
+// Encoding and decoding
+// ----------------------------------------------------------------------------
+
+// DecodeServerRequest decodes a methodCall read from r, returning the
+// method name and storing the parameters in args, which is a pointer to
+// either a struct with a field per parameter or a slice.
+func DecodeServerRequest(r io.Reader, args interface{}) (string, error) {
+	return NewDecoder(defaults...).DecodeServerRequest(r, args)
+}
+
+// DecodeServerRequest is like the package function of the same name,
+// decoding with the options of d.
+func (d *Decoder) DecodeServerRequest(r io.Reader, args interface{}) (string, error) {
+	rawxml, err := d.opts.readAll(r)
+	if err != nil {
+		return "", err
+	}
+	var request ServerRequest
+	decoder := xml.NewDecoder(bytes.NewReader(rawxml))
+	decoder.CharsetReader = charset.NewReader
+	if err := decoder.Decode(&request); err != nil {
+		return "", syntaxError(decoder, string(rawxml), err)
+	}
+	return request.Method, d.xml2RPC(string(rawxml), args)
+}
+
+// EncodeServerResponse encodes reply, a pointer to a struct with a field
+// per returned value, as a methodResponse.
+func EncodeServerResponse(reply interface{}) ([]byte, error) {
+	return NewEncoder(defaults...).EncodeServerResponse(reply)
+}
+
+// EncodeFault encodes fault as a methodResponse.
+func EncodeFault(fault Fault) []byte {
+	return NewEncoder(defaults...).EncodeFault(fault)
+}
@@ -0,0 +1,100 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// ClientStats counts the outcomes of the calls of a Client, including
+// those answered from its cache. A call retried after logging in is
+// counted once, with the outcome of the retry, and the login as a call of
+// its own. Calls whose arguments can't be encoded aren't counted.
+type ClientStats struct {
+	// Successes is the number of calls which returned a result.
+	Successes int64
+	// Faults is the number of calls which returned a fault, by fault code.
+	// The faults the decoder reports for responses it can't convert into
+	// the reply, such as FaultDecode or FaultWrongArgumentsNumber, are
+	// included.
+	Faults map[int]int64
+	// TransportErrors is the number of calls which failed to get a
+	// response, such as for a network error or an HTTP status other than
+	// 200 OK.
+	TransportErrors int64
+	// DecodeErrors is the number of calls whose response couldn't be read
+	// or decoded otherwise, such as a malformed document, reported as a
+	// *SyntaxError, or one larger than the WithMaxBytes limit.
+	DecodeErrors int64
+}
+
+// clientStats holds the counters of a Client, which are updated
+// concurrently.
+type clientStats struct {
+	successes, transportErrors, decodeErrors int64
+
+	mu     sync.Mutex
+	faults map[int]int64
+}
+
+// transportError is the error of a call which got no response.
+type transportError struct {
+	err error
+}
+
+func (e *transportError) Error() string {
+	return e.err.Error()
+}
+
+// encodingError is the error of a call whose request couldn't be encoded,
+// which isn't counted.
+type encodingError struct {
+	err error
+}
+
+func (e *encodingError) Error() string {
+	return e.err.Error()
+}
+
+// Stats returns the counts of the calls of c so far.
+func (c *Client) Stats() ClientStats {
+	stats := ClientStats{
+		Successes:       atomic.LoadInt64(&c.stats.successes),
+		TransportErrors: atomic.LoadInt64(&c.stats.transportErrors),
+		DecodeErrors:    atomic.LoadInt64(&c.stats.decodeErrors),
+		Faults:          make(map[int]int64),
+	}
+	c.stats.mu.Lock()
+	for code, n := range c.stats.faults {
+		stats.Faults[code] = n
+	}
+	c.stats.mu.Unlock()
+	return stats
+}
+
+// record counts the outcome of a call which returned err, returning err
+// with a *transportError or an *encodingError unwrapped.
+func (s *clientStats) record(err error) error {
+	switch e := err.(type) {
+	case nil:
+		atomic.AddInt64(&s.successes, 1)
+	case *encodingError:
+		return e.err
+	case *transportError:
+		atomic.AddInt64(&s.transportErrors, 1)
+		return e.err
+	case Fault:
+		s.mu.Lock()
+		if s.faults == nil {
+			s.faults = make(map[int]int64)
+		}
+		s.faults[e.Code]++
+		s.mu.Unlock()
+	default:
+		atomic.AddInt64(&s.decodeErrors, 1)
+	}
+	return err
+}
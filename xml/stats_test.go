@@ -0,0 +1,74 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestClientStats(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var args struct{ Code int }
+		if _, err := DecodeServerRequest(r.Body, &args); err != nil {
+			t.Error(err)
+		}
+		switch args.Code {
+		case 0:
+			buf, _ := EncodeServerResponse(&struct{ Result string }{"ok"})
+			w.Write(buf)
+		case 503:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 400:
+			w.Write([]byte("<methodResponse><params><param><value><int>1</int></value></param>"))
+		case 413:
+			buf, _ := EncodeServerResponse(&struct{ Result string }{strings.Repeat("a", 1024)})
+			w.Write(buf)
+		default:
+			w.Write(EncodeFault(Fault{Code: args.Code, String: "Failed"}))
+		}
+	}))
+	defer s.Close()
+
+	c := NewClient(s.URL, WithMaxBytes(512))
+	codes := []int{0, 0, 0, 1001, 1001, 2000, 503, 400, 413}
+	var wg sync.WaitGroup
+	for _, code := range codes {
+		wg.Add(1)
+		go func(code int) {
+			defer wg.Done()
+			var reply struct{ Result string }
+			c.Call(context.Background(), "Some.Method", &struct{ Code int }{code}, &reply)
+		}(code)
+	}
+	wg.Wait()
+
+	expected := ClientStats{
+		Successes:       3,
+		Faults:          map[int]int64{1001: 2, 2000: 1},
+		TransportErrors: 1,
+		DecodeErrors:    2,
+	}
+	if stats := c.Stats(); !reflect.DeepEqual(stats, expected) {
+		t.Errorf("expected %+v, but got %+v", expected, stats)
+	}
+
+	var reply struct{ Result string }
+	if err := c.Call(context.Background(), "Some.Method", &struct{ Code int }{400}, &reply); err == nil {
+		t.Error("expected error for a malformed response, but got nil")
+	}
+	if _, ok := c.Call(context.Background(), "Some.Method", &struct{ Code int }{503}, &reply).(*transportError); ok {
+		t.Error("expected the transport error unwrapped")
+	}
+
+	if stats := NewClient(s.URL).Stats(); stats.Successes != 0 || len(stats.Faults) != 0 || stats.TransportErrors != 0 || stats.DecodeErrors != 0 {
+		t.Errorf("expected no calls, but got %+v", stats)
+	}
+}
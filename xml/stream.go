@@ -0,0 +1,167 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"reflect"
+
+	"github.com/rogpeppe/go-charset/charset"
+)
+
+// DefaultStreamThreshold is the default size above which DecodeAuto
+// streams responses.
+const DefaultStreamThreshold = 1 << 20
+
+// WithStreamThreshold sets the size above which DecodeAuto streams
+// responses instead of buffering them.
+func WithStreamThreshold(n int64) Option {
+	return func(o *options) {
+		o.streamThreshold = n
+	}
+}
+
+// DecodeAuto decodes a response into reply like DecodeClientResponse,
+// choosing how by its size: up to the stream threshold, the response is
+// read whole and decoded; above it, params are decoded one by one while
+// the response is read, so that only one param is held in memory at a
+// time. size is the length of the response if known, such as the
+// Content-Length of an HTTP response, or -1, in which case as much of the
+// response as the threshold is read ahead to find out.
+func DecodeAuto(r io.Reader, size int64, reply interface{}) error {
+	return NewDecoder(defaults...).DecodeAuto(r, size, reply)
+}
+
+// DecodeAuto is like the package function of the same name, decoding with
+// the options of d. Documents are always buffered when they are validated.
+func (d *Decoder) DecodeAuto(r io.Reader, size int64, reply interface{}) error {
+	return d.opts.mapError(d.decodeAuto(r, size, reply))
+}
+
+// decodeAuto is like DecodeAuto, returning faults as they are.
+func (d *Decoder) decodeAuto(r io.Reader, size int64, reply interface{}) error {
+	r, stream := d.chooseStream(r, size)
+	if v := reflect.ValueOf(reply).Elem(); v.Kind() == reflect.Struct && rawXMLIndex(v.Type()) >= 0 {
+		// The response is kept whole anyway.
+		stream = false
+	}
+	if !stream {
+		return d.decodeResponse(r, reply)
+	}
+	return d.decodeStream(r, reply)
+}
+
+// chooseStream reports whether a response of the given size should be
+// streamed, returning the reader to decode it from.
+func (d *Decoder) chooseStream(r io.Reader, size int64) (io.Reader, bool) {
+	threshold := d.opts.streamThreshold
+	if d.opts.validate {
+		return r, false
+	}
+	if size >= 0 {
+		return r, size > threshold
+	}
+	var peeked bytes.Buffer
+	n, _ := io.CopyN(&peeked, r, threshold+1)
+	return io.MultiReader(&peeked, r), n > threshold
+}
+
+// decodeStream walks the tokens of the response read from r, decoding each
+// param into reply as soon as it is read.
+func (d *Decoder) decodeStream(r io.Reader, reply interface{}) error {
+	d = d.tracking()
+	if d.opts.maxBytes > 0 {
+		r = &limitedReader{r: r, n: d.opts.maxBytes}
+	}
+	if d.opts.lenient {
+		r = &nameFilter{r: &controlCharFilter{r: r}}
+	}
+	r = d.skipGarbageReader(r)
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = charset.NewReader
+
+	v := reflect.ValueOf(reply).Elem()
+	if v.Kind() == reflect.Slice {
+		v.Set(reflect.MakeSlice(v.Type(), 0, 0))
+	}
+	params := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return syntaxError(decoder, "", err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "fault":
+			var fault faultValue
+			if err := decoder.DecodeElement(&fault, &start); err != nil {
+				return syntaxError(decoder, "", err)
+			}
+			return d.getFaultResponse(fault.Value)
+		case "param":
+			var param Param
+			if err := decoder.DecodeElement(&param, &start); err != nil {
+				return syntaxError(decoder, "", err)
+			}
+			if v.Kind() == reflect.Struct && params >= numParams(v) {
+				if !d.opts.allowExtra {
+					return FaultWrongArgumentsNumber
+				}
+				d.extraParam(params)
+			} else if err := d.param2RPC(params, param, reply); err != nil {
+				return err
+			}
+			params++
+		}
+	}
+	if v.Kind() == reflect.Struct && params < numParams(v) {
+		return FaultWrongArgumentsNumber
+	}
+	return d.errors()
+}
+
+// limitedReader reads from r, failing with ErrTooLarge past n bytes.
+type limitedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return n, ErrTooLarge
+	}
+	return n, err
+}
+
+// controlCharFilter reads from r, skipping the control characters XML
+// doesn't allow. Unlike stripInvalidChars, it keeps references to them.
+type controlCharFilter struct {
+	r io.Reader
+}
+
+func (f *controlCharFilter) Read(p []byte) (int, error) {
+	for {
+		n, err := f.r.Read(p)
+		clean := p[:0]
+		for _, c := range p[:n] {
+			if c >= 0x20 || isXMLChar(rune(c)) {
+				clean = append(clean, c)
+			}
+		}
+		if len(clean) != 0 || err != nil {
+			return len(clean), err
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestChooseStream(t *testing.T) {
+	data := strings.Repeat("x", 100)
+	d := NewDecoder(WithStreamThreshold(100))
+	tests := []struct {
+		data   string
+		size   int64
+		stream bool
+	}{
+		{data, 100, false},
+		{data + "x", 101, true},
+		{data, -1, false},
+		{data + "x", -1, true},
+	}
+	for _, test := range tests {
+		r, stream := d.chooseStream(strings.NewReader(test.data), test.size)
+		if stream != test.stream {
+			t.Errorf("%d bytes, size %d: expected stream to be %v", len(test.data), test.size, test.stream)
+		}
+		if read, _ := ioutil.ReadAll(r); string(read) != test.data {
+			t.Errorf("%d bytes, size %d: expected all data to be read, but got %d bytes", len(test.data), test.size, len(read))
+		}
+	}
+
+	// Without a size, only the threshold and one more byte are read ahead.
+	rest := strings.NewReader(data + data)
+	r, stream := d.chooseStream(rest, -1)
+	if !stream || rest.Len() != len(data)-1 {
+		t.Errorf("expected 101 bytes to be peeked, but got %d", 2*len(data)-rest.Len())
+	}
+	if read, _ := ioutil.ReadAll(r); string(read) != data+data {
+		t.Errorf("expected the peeked bytes to be read first, but got %d bytes", len(read))
+	}
+
+	if _, stream := NewDecoder(WithStreamThreshold(10), ValidateSyntax()).chooseStream(strings.NewReader(data), 100); stream {
+		t.Error("expected validated documents not to be streamed")
+	}
+}
+
+func TestDecodeAutoPath(t *testing.T) {
+	// The response fails after its first param, which is only decoded if
+	// the response is streamed.
+	data := "<methodResponse><params><param><value><int>1</int></value></param><param>"
+	tests := []struct {
+		threshold int64
+		size      int64
+		params    int
+	}{
+		{int64(len(data)), int64(len(data)), 0},
+		{int64(len(data)) - 1, int64(len(data)), 1},
+		{int64(len(data)), -1, 0},
+		{int64(len(data)) - 1, -1, 1},
+	}
+	for _, test := range tests {
+		var params []interface{}
+		r := io.MultiReader(strings.NewReader(data), iotest.ErrReader(errors.New("connection reset")))
+		if err := NewDecoder(WithStreamThreshold(test.threshold)).DecodeAuto(r, test.size, &params); err == nil {
+			t.Errorf("threshold %d, size %d: expected error, but got nil", test.threshold, test.size)
+		}
+		if len(params) != test.params {
+			t.Errorf("threshold %d, size %d: expected %d params decoded, but got %v", test.threshold, test.size, test.params, params)
+		}
+	}
+}
+
+func TestDecodeAuto(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/bench_array.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := int64(len(data))
+
+	buffered := new(struct{ People []BenchPerson })
+	if err := NewDecoder(WithStreamThreshold(size)).DecodeAuto(bytes.NewReader(data), size, buffered); err != nil {
+		t.Fatal(err)
+	}
+	streamed := new(struct{ People []BenchPerson })
+	if err := NewDecoder(WithStreamThreshold(size-1)).DecodeAuto(bytes.NewReader(data), size, streamed); err != nil {
+		t.Fatal(err)
+	}
+	if len(streamed.People) != 250 || !reflect.DeepEqual(buffered, streamed) {
+		t.Error("expected streamed and buffered decoding to be equal")
+	}
+
+	var params []interface{}
+	d := NewDecoder(WithStreamThreshold(0))
+	if err := d.DecodeAuto(strings.NewReader("<methodResponse><params><param><value><int>1</int></value></param><param><value>two</value></param></params></methodResponse>"), -1, &params); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(params, []interface{}{1, "two"}) {
+		t.Errorf("wrong params: %v", params)
+	}
+
+	err = d.DecodeAuto(strings.NewReader("<methodResponse><params><param><value><int>1</int></value></param><param><value>two</value></param></params></methodResponse>"), -1, new(struct{ N int }))
+	if fault, ok := err.(Fault); !ok || fault.Code != FaultWrongArgumentsNumber.Code {
+		t.Errorf("expected FaultWrongArgumentsNumber, but got %v", err)
+	}
+
+	err = d.DecodeAuto(strings.NewReader("<methodResponse><fault><value><struct><member><name>faultCode</name><value><int>4</int></value></member><member><name>faultString</name><value><string>Too many params</string></value></member></struct></value></fault></methodResponse>"), -1, new(struct{ N int }))
+	if fault, ok := err.(Fault); !ok || fault.Code != 4 {
+		t.Errorf("expected fault 4, but got %v", err)
+	}
+
+	err = NewDecoder(WithStreamThreshold(0), WithMaxBytes(100)).DecodeAuto(bytes.NewReader(data), size, buffered)
+	if err != ErrTooLarge {
+		t.Errorf("expected ErrTooLarge, but got %v", err)
+	}
+}
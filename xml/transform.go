@@ -0,0 +1,241 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParamTransformer rewrites the parameters of a call to method before the
+// request is sent, for instance to add credentials.
+type ParamTransformer func(method string, params []Value) ([]Value, error)
+
+// WithParamTransformer makes the encoder pass the parameters of every
+// client request through transform. Transformers are applied in the order
+// they are given. The calls of a system.multicall are transformed one by
+// one, with their own method and params.
+func WithParamTransformer(transform ParamTransformer) Option {
+	return func(o *options) {
+		o.transformers = append(o.transformers, transform)
+	}
+}
+
+// WithLeadingParams makes the encoder insert params before the parameters
+// of every client request, as required by APIs expecting credentials as
+// the first parameters.
+func WithLeadingParams(params ...interface{}) Option {
+	return WithLeadingParamsFunc(func() []interface{} { return params })
+}
+
+// WithLeadingParamsFunc is like WithLeadingParams, with the params returned
+// by params for each request, such as a session token which may be
+// refreshed.
+func WithLeadingParamsFunc(params func() []interface{}) Option {
+	return WithParamTransformer(func(method string, values []Value) ([]Value, error) {
+		params := params()
+		leading := make([]Value, len(params), len(params)+len(values))
+		for i, param := range params {
+			v, err := toValue(param)
+			if err != nil {
+				return nil, err
+			}
+			leading[i] = v
+		}
+		return append(leading, values...), nil
+	})
+}
+
+// WithStructMember makes the encoder set the member name to value in the
+// first struct parameter of every client request, replacing any member of
+// the same name. Requests without a struct parameter fail.
+func WithStructMember(name string, value interface{}) Option {
+	return WithParamTransformer(func(method string, values []Value) ([]Value, error) {
+		v, err := toValue(value)
+		if err != nil {
+			return nil, err
+		}
+		for i := range values {
+			if len(values[i].Struct) == 0 && rawType(values[i].Raw) != "struct" {
+				continue
+			}
+			members := make([]Member, 0, len(values[i].Struct)+1)
+			for _, m := range values[i].Struct {
+				if m.Name != name {
+					members = append(members, m)
+				}
+			}
+			values[i].Struct = append(members, Member{Name: name, Value: v})
+			return values, nil
+		}
+		return nil, fmt.Errorf("xml: no struct parameter in call to %s for member %s", method, name)
+	})
+}
+
+// toValue converts x into a Value, as it would be encoded.
+func toValue(x interface{}) (Value, error) {
+	var v Value
+	data, err := NewEncoder().rpc2XML(x)
+	if err != nil {
+		return v, err
+	}
+	err = xml.Unmarshal([]byte(data), &v)
+	return v, err
+}
+
+// transformParams applies the parameter transformers to the methodCall
+// xmlraw, returning the transformed methodCall.
+func (e *Encoder) transformParams(method, xmlraw string) (string, error) {
+	doc, err := parseDocument(strings.NewReader(xmlraw))
+	if err != nil {
+		return "", err
+	}
+	params := make([]Value, len(doc.Params))
+	for i, p := range doc.Params {
+		params[i] = p.Value
+	}
+	if method == "system.multicall" && len(params) == 1 && len(params[0].Array) != 0 {
+		err = e.transformMulticall(params[0].Array)
+	} else {
+		params, err = e.transform(method, params)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	xml, err := e.params2XML(params)
+	if err != nil {
+		return "", err
+	}
+	buffer := e.root("methodCall")
+	buffer += "<methodName>" + method + "</methodName>"
+	buffer += xml
+	buffer += "</methodCall>"
+	return buffer, nil
+}
+
+// transform applies the parameter transformers to params, the parameters
+// of a call to method.
+func (e *Encoder) transform(method string, params []Value) ([]Value, error) {
+	var err error
+	for _, transform := range e.opts.transformers {
+		if params, err = transform(method, params); err != nil {
+			return nil, err
+		}
+	}
+	return params, nil
+}
+
+// transformMulticall applies the parameter transformers to the params of
+// each call of a system.multicall, with the name of the method called, as
+// if the calls were sent one by one.
+func (e *Encoder) transformMulticall(calls []Value) error {
+	for i, call := range calls {
+		method, index := "", -1
+		for j, m := range call.Struct {
+			switch m.Name {
+			case "methodName":
+				method, _ = stringText(m.Value)
+			case "params":
+				index = j
+			}
+		}
+		var params []Value
+		if index >= 0 {
+			params = call.Struct[index].Value.Array
+		}
+		params, err := e.transform(method, params)
+		if err != nil {
+			return fmt.Errorf("xml: call %d of system.multicall: %v", i, err)
+		}
+		members := append([]Member(nil), call.Struct...)
+		value := Value{Array: params, Raw: "<array><data></data></array>"}
+		if index >= 0 {
+			members[index].Value = value
+		} else {
+			members = append(members, Member{Name: "params", Value: value})
+		}
+		calls[i].Struct = members
+	}
+	return nil
+}
+
+// params2XML encodes the values of params.
+func (e *Encoder) params2XML(params []Value) (string, error) {
+	buffer := "<params>"
+	for i, p := range params {
+		xml, err := e.value2XML(p)
+		if err != nil {
+			return "", encodeError("params["+strconv.Itoa(i)+"]", err)
+		}
+		buffer += "<param>" + xml + "</param>"
+	}
+	buffer += "</params>"
+	return buffer, nil
+}
+
+// value2XML encodes v with the options of e, as rpc2XML would encode the
+// value it holds. Values without a typed field, such as empty strings, or
+// of types the package doesn't know are written as they were received.
+func (e *Encoder) value2XML(v Value) (string, error) {
+	scalar := func(name, text string) string {
+		return "<" + name + ">" + escapeString(text) + "</" + name + ">"
+	}
+	out := "<value>"
+	switch {
+	case len(v.Struct) != 0:
+		out += "<struct>"
+		for _, m := range v.Struct {
+			xml, err := e.value2XML(m.Value)
+			if err != nil {
+				return "", encodeError("."+m.Name, err)
+			}
+			out += "<member><name>" + escapeString(m.Name) + "</name>" + xml + "</member>"
+		}
+		out += "</struct>"
+	case len(v.Array) != 0:
+		out += "<array><data>"
+		for i, item := range v.Array {
+			xml, err := e.value2XML(item)
+			if err != nil {
+				return "", encodeError("["+strconv.Itoa(i)+"]", err)
+			}
+			out += xml
+		}
+		out += "</data></array>"
+	case v.String != "":
+		xml, err := e.string2XML(v.String)
+		if err != nil {
+			return "", err
+		}
+		out += xml
+	case v.Int != "":
+		out += scalar("int", v.Int)
+	case v.Int4 != "":
+		out += scalar("i4", v.Int4)
+	case v.Int8 != "":
+		if e.opts.apacheExtensions {
+			out += scalar("ex:i8", v.Int8)
+		} else {
+			out += scalar("i8", v.Int8)
+		}
+	case v.Double != "":
+		out += scalar("double", v.Double)
+	case v.Boolean != "":
+		out += scalar("boolean", v.Boolean)
+	case v.DateTime != "":
+		out += scalar("dateTime.iso8601", v.DateTime)
+	case v.Base64 != "":
+		out += scalar("base64", v.Base64)
+	case rawType(v.Raw) == "nil":
+		out += e.nil2XML()
+	default:
+		out += v.Raw
+	}
+	out += "</value>"
+	return out, nil
+}
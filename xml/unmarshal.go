@@ -0,0 +1,52 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"context"
+	"reflect"
+)
+
+// Unmarshaler is implemented by types which decode values themselves. The
+// decoder calls UnmarshalXMLRPC on a pointer to the field, with the value
+// to decode into it.
+type Unmarshaler interface {
+	UnmarshalXMLRPC(value Value) error
+}
+
+// ContextUnmarshaler is an Unmarshaler for decodings which may take long,
+// such as by looking values up, and should honor cancellation. It is
+// passed the context of the decoding, such as that of Client.Call, or
+// context.Background if there is none. It is preferred to UnmarshalXMLRPC
+// when a type implements both.
+type ContextUnmarshaler interface {
+	UnmarshalXMLRPCContext(ctx context.Context, value Value) error
+}
+
+var (
+	unmarshalerType        = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+	contextUnmarshalerType = reflect.TypeOf((*ContextUnmarshaler)(nil)).Elem()
+)
+
+// unmarshal decodes value into field with the methods of its type, if it
+// implements ContextUnmarshaler or Unmarshaler. It reports whether it did.
+func (d *Decoder) unmarshal(value Value, field reflect.Value) (bool, error) {
+	if field.Kind() == reflect.Ptr || !field.CanAddr() {
+		// Pointers are allocated first, and their target decoded.
+		return false, nil
+	}
+	ptr := field.Addr()
+	switch {
+	case ptr.Type().Implements(contextUnmarshalerType):
+		ctx := d.ctx
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		return true, ptr.Interface().(ContextUnmarshaler).UnmarshalXMLRPCContext(ctx, value)
+	case ptr.Type().Implements(unmarshalerType):
+		return true, ptr.Interface().(Unmarshaler).UnmarshalXMLRPC(value)
+	}
+	return false, nil
+}
@@ -0,0 +1,78 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// upperString decodes strings in upper case.
+type upperString string
+
+func (s *upperString) UnmarshalXMLRPC(value Value) error {
+	*s = upperString(strings.ToUpper(value.String))
+	return nil
+}
+
+// slowLookup resolves the ids it is sent with a lookup taking a second,
+// unless its context is done first.
+type slowLookup struct {
+	Name string
+}
+
+func (l *slowLookup) UnmarshalXMLRPCContext(ctx context.Context, value Value) error {
+	select {
+	case <-time.After(time.Second):
+		l.Name = "user " + value.Int
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+const unmarshalResponse = "<methodResponse><params><param><value><string>ivan</string></value></param><param><value><int>42</int></value></param></params></methodResponse>"
+
+func TestUnmarshaler(t *testing.T) {
+	var reply struct {
+		Name  upperString
+		Owner *slowLookup
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := NewDecoder().DecodeClientResponseContext(ctx, strings.NewReader(unmarshalResponse), &reply)
+	if reply.Name != "IVAN" {
+		t.Errorf("expected IVAN, but got %q", reply.Name)
+	}
+	if err != context.Canceled {
+		t.Errorf("expected %v, but got %v", context.Canceled, err)
+	}
+}
+
+func TestClientContextUnmarshaler(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(unmarshalResponse))
+	}))
+	defer s.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	var reply struct {
+		Name  upperString
+		Owner slowLookup
+	}
+	start := time.Now()
+	err := NewClient(s.URL).Call(ctx, "Users.Get", &struct{}{}, &reply)
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected %v, but got %v", context.DeadlineExceeded, err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected the lookup to stop at the deadline, but it took %v", elapsed)
+	}
+}
@@ -0,0 +1,230 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rogpeppe/go-charset/charset"
+)
+
+// ValidateRequest checks that r holds a well-formed XML-RPC methodCall,
+// without decoding its parameters. All violations are reported as
+// SyntaxErrors. The limits set by opts apply as for decoding.
+func ValidateRequest(r io.Reader, opts ...Option) error {
+	return validateReader(r, "methodCall", newOptions(opts))
+}
+
+// ValidateResponse checks that r holds a well-formed XML-RPC
+// methodResponse, reporting violations the same way as ValidateRequest.
+func ValidateResponse(r io.Reader, opts ...Option) error {
+	return validateReader(r, "methodResponse", newOptions(opts))
+}
+
+func validateReader(r io.Reader, root string, o *options) error {
+	rawxml, err := o.readAll(r)
+	if err != nil {
+		return err
+	}
+	if errs := validate(string(rawxml), root, o); len(errs) != 0 {
+		return errs
+	}
+	return nil
+}
+
+// parents lists the elements each XML-RPC element may be nested in.
+// Document roots have no parents.
+var parents = map[string][]string{
+	"methodCall":       nil,
+	"methodResponse":   nil,
+	"methodName":       {"methodCall"},
+	"params":           {"methodCall", "methodResponse"},
+	"param":            {"params"},
+	"fault":            {"methodResponse"},
+	"value":            {"param", "member", "data", "fault"},
+	"struct":           {"value"},
+	"member":           {"struct"},
+	"name":             {"member"},
+	"array":            {"value"},
+	"data":             {"array"},
+	"int":              {"value"},
+	"i4":               {"value"},
+	"i8":               {"value"},
+	"double":           {"value"},
+	"boolean":          {"value"},
+	"string":           {"value"},
+	"dateTime.iso8601": {"value"},
+	"base64":           {"value"},
+	"nil":              {"value"},
+}
+
+// textElements are the elements which may contain character data.
+var textElements = map[string]bool{
+	"methodName":       true,
+	"name":             true,
+	"value":            true,
+	"int":              true,
+	"i4":               true,
+	"i8":               true,
+	"double":           true,
+	"boolean":          true,
+	"string":           true,
+	"dateTime.iso8601": true,
+	"base64":           true,
+}
+
+// validate checks that xmlraw is a well-formed XML-RPC document with the
+// given root element, or either root if it is empty. Validation stops at
+// the first error which leaves the document unreadable.
+func validate(xmlraw string, root string, o *options) SyntaxErrors {
+	decoder := xml.NewDecoder(strings.NewReader(xmlraw))
+	decoder.CharsetReader = charset.NewReader
+
+	type frame struct {
+		name     string
+		children []string
+	}
+	var (
+		errs  SyntaxErrors
+		stack []frame
+		depth int
+		seen  bool
+	)
+
+	line, column := decoder.InputPos()
+	offset := decoder.InputOffset()
+	report := func(format string, args ...interface{}) {
+		errs = append(errs, newSyntaxError(xmlraw, fmt.Sprintf(format, args...), line, column, offset))
+	}
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if serr, ok := err.(*xml.SyntaxError); ok {
+				errs = append(errs, syntaxError(decoder, xmlraw, serr).(*SyntaxError))
+			} else {
+				report("%v", err)
+			}
+			return errs
+		}
+
+		switch t := token.(type) {
+		case xml.Directive:
+			if bytes.HasPrefix(bytes.TrimSpace(t), []byte("DOCTYPE")) {
+				report("DOCTYPE is not allowed")
+			}
+
+		case xml.StartElement:
+			name := t.Name.Local
+			if len(stack) == 0 {
+				switch {
+				case seen:
+					report("unexpected <%s> after document root", name)
+				case root != "" && name != root:
+					report("unexpected root element <%s>, expected <%s>", name, root)
+				case root == "" && name != "methodCall" && name != "methodResponse":
+					report("unexpected root element <%s>", name)
+				}
+				seen = true
+				stack = append(stack, frame{name: name})
+				break
+			}
+
+			parent := &stack[len(stack)-1]
+			parent.children = append(parent.children, name)
+			if parent.name == "value" && len(parent.children) == 2 {
+				report("<value> contains more than one type")
+			}
+			allowed, known := parents[name]
+			if !known {
+				if parent.name == "value" {
+					report("unknown type <%s>", name)
+				} else {
+					report("unexpected <%s> in <%s>", name, parent.name)
+				}
+				// The content of unknown elements is not checked.
+				if err := decoder.Skip(); err != nil {
+					report("%v", err)
+					return errs
+				}
+				break
+			}
+			if !contains(allowed, parent.name) {
+				report("unexpected <%s> in <%s>, expected inside <%s>",
+					name, parent.name, strings.Join(allowed, ">, <"))
+			}
+			if name == "value" {
+				depth++
+				if o.maxDepth > 0 && depth > o.maxDepth {
+					report("values nested deeper than %d levels", o.maxDepth)
+					return errs
+				}
+			}
+			stack = append(stack, frame{name: name})
+
+		case xml.EndElement:
+			current := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			switch current.name {
+			case "value":
+				depth--
+			case "methodCall":
+				if !contains(current.children, "methodName") {
+					report("<methodCall> must contain a <methodName>")
+				}
+			case "methodResponse":
+				hasParams := contains(current.children, "params")
+				hasFault := contains(current.children, "fault")
+				if hasParams == hasFault {
+					report("<methodResponse> must contain either <params> or <fault>")
+				}
+			case "member":
+				if len(current.children) != 2 || !contains(current.children, "name") || !contains(current.children, "value") {
+					report("<member> must contain a <name> and a <value>")
+				}
+			case "param", "fault":
+				if len(current.children) != 1 || current.children[0] != "value" {
+					report("<%s> must contain exactly one <value>", current.name)
+				}
+			}
+
+		case xml.CharData:
+			if len(stack) == 0 || len(bytes.TrimSpace(t)) == 0 {
+				break
+			}
+			if name := stack[len(stack)-1].name; !textElements[name] {
+				report("unexpected text in <%s>", name)
+			}
+		}
+
+		line, column = decoder.InputPos()
+		offset = decoder.InputOffset()
+	}
+
+	if !seen {
+		if root == "" {
+			report("missing <methodCall> or <methodResponse> element")
+		} else {
+			report("missing <%s> element", root)
+		}
+	}
+	return errs
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	valid := []string{
+		"<methodCall><methodName>Some.Method</methodName><params><param><value><i4>123</i4></value></param></params></methodCall>",
+		"<methodResponse><params><param><value><struct><member><name>Foo</name><value>bar</value></member></struct></value></param></params></methodResponse>",
+		"<methodResponse><params><param><value><array><data><value><int>1</int></value><value><nil/></value></data></array></value></param></params></methodResponse>",
+		"<?xml version=\"1.0\"?>\n<methodResponse><fault><value><struct><member><name>faultCode</name><value><int>4</int></value></member></struct></value></fault></methodResponse>",
+	}
+	for _, doc := range valid {
+		if errs := validate(doc, "", newOptions(nil)); len(errs) != 0 {
+			t.Errorf("validate(%q) returned %v", doc, errs)
+		}
+	}
+
+	invalid := []struct {
+		doc  string
+		line int
+		msg  string
+	}{
+		{"<methodResponse><params><param><value><member><name>Foo</name><value>bar</value></member></value></param></params></methodResponse>",
+			1, "unexpected <member> in <value>, expected inside <struct>"},
+		{"<methodResponse>\n<params>\n<param><value><int>1</int><string>a</string></value></param></params></methodResponse>",
+			3, "<value> contains more than one type"},
+		{"<methodResponse><params><param><value><int>1</value></param></params></methodResponse>",
+			1, "element <int> closed by </value>"},
+		{"<methodResponse><params><value><int>1</int></value></params></methodResponse>",
+			1, "unexpected <value> in <params>"},
+		{"<response><params></params></response>",
+			1, "unexpected root element <response>"},
+		{"<methodResponse><params><param><value><struct><member><value>1</value></member></struct></value></param></params></methodResponse>",
+			1, "<member> must contain a <name> and a <value>"},
+		{"<methodResponse><params>oops</params></methodResponse>",
+			1, "unexpected text in <params>"},
+	}
+	for _, tt := range invalid {
+		errs := validate(tt.doc, "", newOptions(nil))
+		if len(errs) == 0 {
+			t.Errorf("validate(%q): expected an error", tt.doc)
+			continue
+		}
+		serr := errs[0]
+		if serr.Line != tt.line || !strings.HasPrefix(serr.Msg, tt.msg) {
+			t.Errorf("validate(%q): expected %q on line %d, but got %q on line %d", tt.doc, tt.msg, tt.line, serr.Msg, serr.Line)
+		}
+	}
+}
+
+func TestDecoderValidateSyntax(t *testing.T) {
+	data := "<methodResponse><params><param><value><member><name>Foo</name><value><int>42</int></value></member></value></param></params></methodResponse>"
+
+	var res struct{ Foo struct{ Foo int } }
+	if err := NewDecoder().DecodeClientResponse(strings.NewReader(data), &res); err != nil {
+		t.Errorf("expected lax decoding to succeed, but got %v", err)
+	}
+
+	err := NewDecoder(ValidateSyntax()).DecodeClientResponse(strings.NewReader(data), &res)
+	if _, ok := err.(*SyntaxError); !ok {
+		t.Errorf("expected *SyntaxError, but got %v", err)
+	}
+}
+
+func TestValidateResponse(t *testing.T) {
+	data := `<?xml version="1.0"?>
+<!DOCTYPE methodResponse>
+<methodResponse>
+  <params>
+    <param><value><ex:float>5</ex:float></value></param>
+    <param><value><struct><member><value>1</value></member></struct></value></param>
+  </params>
+  <fault><value><string>oops</string></value></fault>
+</methodResponse>`
+
+	err := ValidateResponse(strings.NewReader(data))
+	errs, ok := err.(SyntaxErrors)
+	if !ok {
+		t.Fatalf("expected SyntaxErrors, but got %v", err)
+	}
+	expected := []SyntaxError{
+		{Msg: "DOCTYPE is not allowed", Line: 2, Column: 1},
+		{Msg: "unknown type <float>", Line: 5, Column: 19},
+		{Msg: "<member> must contain a <name> and a <value>", Line: 6, Column: 51},
+		{Msg: "<methodResponse> must contain either <params> or <fault>", Line: 9, Column: 1},
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("expected %d errors, but got %d: %v", len(expected), len(errs), errs)
+	}
+	for i, e := range expected {
+		if errs[i].Msg != e.Msg || errs[i].Line != e.Line || errs[i].Column != e.Column {
+			t.Errorf("error %d: expected %v, but got %v", i, &e, errs[i])
+		}
+	}
+
+	err = ValidateRequest(strings.NewReader(data))
+	errs, ok = err.(SyntaxErrors)
+	if !ok || errs[1].Msg != "unexpected root element <methodResponse>, expected <methodCall>" {
+		t.Errorf("expected wrong root element to be reported, but got %v", err)
+	}
+}
+
+func TestValidateLimits(t *testing.T) {
+	data := "<methodCall><methodName>Deep</methodName><params><param>" +
+		strings.Repeat("<value><array><data>", 5) + strings.Repeat("</data></array></value>", 5) +
+		"</param></params></methodCall>"
+
+	if err := ValidateRequest(strings.NewReader(data)); err != nil {
+		t.Errorf("expected document to be valid, but got %v", err)
+	}
+	err := ValidateRequest(strings.NewReader(data), WithMaxDepth(4))
+	if errs, ok := err.(SyntaxErrors); !ok || errs[0].Msg != "values nested deeper than 4 levels" {
+		t.Errorf("expected depth limit error, but got %v", err)
+	}
+	if err := ValidateRequest(strings.NewReader(data), WithMaxBytes(64)); err != ErrTooLarge {
+		t.Errorf("expected ErrTooLarge, but got %v", err)
+	}
+}
@@ -8,9 +8,14 @@ import (
 	"bytes"
 	"encoding/base64"
 	"encoding/xml"
+	"errors"
 	"fmt"
+	"io"
+	"net/url"
 	"reflect"
+	"regexp"
 	"strconv"
+	"strings"
 	"time"
 	"unicode"
 	"unicode/utf8"
@@ -19,23 +24,41 @@ import (
 	_ "github.com/rogpeppe/go-charset/data"
 )
 
-// Types used for unmarshalling
-type response struct {
-	Name   xml.Name   `xml:"methodResponse"`
-	Params []param    `xml:"params>param"`
-	Fault  faultValue `xml:"fault,omitempty"`
+// Response is the tree of a methodResponse, with its values as they were
+// received, as returned by DecodeResponseTree. Fault is set to the value
+// of the <fault> element if there is one, and Params are empty then.
+type Response struct {
+	XMLName xml.Name
+	Params  []Param `xml:"params>param"`
+	Fault   *Value  `xml:"fault>value"`
 }
 
-type param struct {
-	Value value `xml:"value"`
+// isFault reports whether r is a fault, which has a struct value.
+func (r *Response) isFault() bool {
+	return r.Fault != nil && len(r.Fault.Struct) != 0
 }
 
-type value struct {
-	Array    []value  `xml:"array>data>value"`
-	Struct   []member `xml:"struct>member"`
+// Param is a parameter of a methodCall or a methodResponse.
+type Param struct {
+	Value Value `xml:"value"`
+}
+
+// Value is the intermediate representation of an XML-RPC value. Exactly
+// one of its typed fields is normally set; Raw holds the inner XML of the
+// value as received. Scalars hold their text untrimmed and unconverted, so
+// that empty elements, such as <string/>, and untyped values leave the
+// typed fields empty and are told apart by Raw.
+//
+// Elements are matched by local name, so that extension types such as
+// <ex:i8> are recognised whichever prefix their namespace is bound to.
+type Value struct {
+	Array    []Value  `xml:"array>data>value"`
+	Data     []Value  `xml:"data>value"` // items of an array without <array>, in lenient mode
+	Struct   []Member `xml:"struct>member"`
 	String   string   `xml:"string"`
 	Int      string   `xml:"int"`
 	Int4     string   `xml:"i4"`
+	Int8     string   `xml:"i8"`
 	Double   string   `xml:"double"`
 	Boolean  string   `xml:"boolean"`
 	DateTime string   `xml:"dateTime.iso8601"`
@@ -43,126 +66,379 @@ type value struct {
 	Raw      string   `xml:",innerxml"` // the value can be defualt string
 }
 
-type member struct {
+// Integer returns the text of v if it is an integer, whether <int>, <i4>
+// or <i8>.
+func (v Value) Integer() (string, bool) {
+	for _, text := range []string{v.Int, v.Int4, v.Int8} {
+		if text != "" {
+			return text, true
+		}
+	}
+	return "", false
+}
+
+// Member is a named member of an XML-RPC struct.
+type Member struct {
 	Name  string `xml:"name"`
-	Value value  `xml:"value"`
+	Value Value  `xml:"value"`
 }
 
 func xml2RPC(xmlraw string, rpc interface{}) error {
-	// Unmarshal raw XML into the temporal structure
-	var ret response
+	return NewDecoder(defaults...).xml2RPC(xmlraw, rpc)
+}
+
+// parseResponse unmarshals xmlraw into the temporal structure.
+func (d *Decoder) parseResponse(xmlraw string) (*Response, error) {
+	if d.opts.lenient {
+		xmlraw = string(canonicalNames([]byte(xmlraw)))
+	}
+	if len(d.opts.envelope) != 0 {
+		var err error
+		if xmlraw, err = unwrapEnvelope(xmlraw, d.opts.envelope); err != nil {
+			return nil, err
+		}
+	}
+	if d.opts.validate {
+		if errs := validate(xmlraw, "", d.opts); len(errs) != 0 {
+			return nil, errs[0]
+		}
+	}
+
+	var ret Response
 	decoder := xml.NewDecoder(bytes.NewReader([]byte(xmlraw)))
 	decoder.CharsetReader = charset.NewReader
 	err := decoder.Decode(&ret)
 	if err != nil {
-		return FaultDecode
+		if _, ok := err.(*xml.SyntaxError); ok {
+			return nil, syntaxError(decoder, xmlraw, err)
+		}
+		return nil, FaultDecode
+	}
+	return &ret, nil
+}
+
+func (d *Decoder) xml2RPC(xmlraw string, rpc interface{}) error {
+	d = d.tracking()
+
+	// Unmarshal raw XML into the temporal structure
+	ret, err := d.parseResponse(xmlraw)
+	if err != nil {
+		return err
 	}
 
-	if !ret.Fault.IsEmpty() {
-		return getFaultResponse(ret.Fault)
+	if ret.isFault() {
+		return d.getFaultResponse(*ret.Fault)
 	}
 
-	// Structures should have equal number of fields
-	if reflect.TypeOf(rpc).Elem().NumField() != len(ret.Params) {
+	// Parameters may be decoded into a slice, such as []interface{}
+	params := ret.Params
+	if v := reflect.ValueOf(rpc).Elem(); v.Kind() == reflect.Slice {
+		v.Set(reflect.MakeSlice(v.Type(), len(params), len(params)))
+	} else if n := numParams(v); n < len(params) && d.opts.allowExtra {
+		for i := n; i < len(params); i++ {
+			d.extraParam(i)
+		}
+		params = params[:n]
+	} else if n != len(params) {
+		// Structures should have equal number of fields
 		return FaultWrongArgumentsNumber
 	}
 
 	// Now, convert temporal structure into the
 	// passed rpc variable, according to it's structure
-	for i, param := range ret.Params {
-		field := reflect.ValueOf(rpc).Elem().Field(i)
-		err = value2Field(param.Value, &field)
-		if err != nil {
+	for i, param := range params {
+		if err = d.param2RPC(i, param, rpc); err != nil {
 			return err
 		}
 	}
 
-	return nil
+	return d.errors()
+}
+
+// param2RPC converts the i-th param into rpc, a pointer to either a struct
+// with a field per param, or a slice which is grown as needed.
+func (d *Decoder) param2RPC(i int, param Param, rpc interface{}) error {
+	v := reflect.ValueOf(rpc).Elem()
+	var (
+		field reflect.Value
+		info  fieldInfo
+	)
+	if v.Kind() == reflect.Slice {
+		if i >= v.Len() {
+			v.Set(reflect.Append(v, reflect.Zero(v.Type().Elem())))
+		}
+		field = v.Index(i)
+	} else {
+		field, info = paramField(v, i)
+	}
+	return d.at("params["+strconv.Itoa(i)+"]", func() error {
+		// Params are decoded as members, with the options of their tag.
+		return d.member2Field(param.Value, &field, info)
+	})
 }
 
-// getFaultResponse converts faultValue to Fault.
-func getFaultResponse(fault faultValue) Fault {
+// extraParam reports that the i-th param is ignored, as the reply has no
+// field for it.
+func (d *Decoder) extraParam(i int) {
+	d.at("params["+strconv.Itoa(i)+"]", func() error {
+		d.warn(errors.New("extra param ignored"))
+		return nil
+	})
+}
+
+// getFaultResponse converts the value of a <fault> element to Fault.
+func (d *Decoder) getFaultResponse(fault Value) Fault {
 	var (
 		code int
 		str  string
 	)
+	detail := make(map[string]interface{})
 
-	for _, field := range fault.Value.Struct {
+	for _, field := range fault.Struct {
 		if field.Name == "faultCode" {
-			code, _ = strconv.Atoi(field.Value.Int)
+			text, _ := field.Value.Integer()
+			code, _ = strconv.Atoi(strings.TrimSpace(text))
 		} else if field.Name == "faultString" {
-			str = field.Value.String
-			if str == "" {
-				str = field.Value.Raw
-			}
+			// The text around a <string> element, such as indentation,
+			// isn't part of the string.
+			str, _ = stringText(field.Value)
+		} else {
+			detail[field.Name], _ = d.value2Interface(field.Value)
 		}
 	}
 
-	return Fault{Code: code, String: str}
+	return Fault{Code: code, String: str}.withDetail(detail)
 }
 
-func value2Field(value value, field *reflect.Value) error {
+func (d *Decoder) value2Field(value Value, field *reflect.Value) error {
 	if !field.CanSet() {
 		return FaultApplicationError
 	}
+	if d.opts.lenient {
+		value = bareArray(value)
+	}
+	switch field.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Complex64, reflect.Complex128, reflect.UnsafePointer:
+		// No XML-RPC type converts to these.
+		fault := FaultInvalidParams
+		fault.String += fmt.Sprintf(": unsupported field type %s", field.Type())
+		return fault
+	}
+	if field.Type() == rawValueType {
+		field.SetString(value.Raw)
+		return nil
+	}
+	if ok, err := d.unmarshal(value, *field); ok {
+		return err
+	}
+	value, err := d.firstType(value)
+	if err != nil {
+		return err
+	}
+	if field.Kind() == reflect.Ptr {
+		return d.value2Pointer(value, field)
+	}
+	if ok, err := d.number2Field(value, *field); ok {
+		return err
+	}
+	if field.Type() == timeType && d.opts.epochTimes {
+		if t, ok, err := d.epoch2Time(value); ok {
+			if err == nil {
+				field.Set(reflect.ValueOf(t))
+			}
+			return err
+		}
+	}
+	if field.Type() == rawMessageType {
+		data, err := d.valueToJSON(value)
+		if err == nil {
+			field.SetBytes(data)
+		}
+		return err
+	}
+	if field.Kind() == reflect.Interface && field.NumMethod() == 0 {
+		val, err := d.value2Interface(value)
+		if val != nil {
+			field.Set(reflect.ValueOf(val))
+		}
+		return err
+	}
+	if d.opts.lenient && field.Kind() == reflect.Slice && field.Type().Elem().Kind() != reflect.Uint8 && singleValue(value) {
+		// Some servers send a single item without wrapping it in an array.
+		value = Value{Array: []Value{value}, Raw: "<array><data><value>" + value.Raw + "</value></data></array>"}
+	}
 
 	var (
-		err error
-		val interface{}
+		parseErr error // only reported when errors are collected
+		val      interface{}
 	)
 
+	integer, isInteger := value.Integer()
 	switch {
-	case value.Int != "":
-		val, _ = strconv.Atoi(value.Int)
-	case value.Int4 != "":
-		val, _ = strconv.Atoi(value.Int4)
+	case isInteger:
+		if err := d.checkInteger(integer); err != nil {
+			return err
+		}
+		var n int64
+		n, parseErr = strconv.ParseInt(strings.TrimSpace(integer), 10, 64)
+		// <i8> values are int64, unless decoded into an int.
+		if value.Int8 != integer || field.Kind() == reflect.Int {
+			val = int(n)
+		} else {
+			val = n
+		}
 	case value.Double != "":
-		val, _ = strconv.ParseFloat(value.Double, 64)
+		if field.Kind() == reflect.String {
+			// Keep the exact text, which a float64 may not represent.
+			val = strings.TrimSpace(value.Double)
+		} else {
+			val, parseErr = strconv.ParseFloat(value.Double, 64)
+		}
 	case value.String != "":
 		val = value.String
 	case value.Boolean != "":
 		val = xml2Bool(value.Boolean)
 	case value.DateTime != "":
-		val, err = xml2DateTime(value.DateTime)
+		val, err = d.xml2DateTime(value.DateTime, rawName(value.Raw).Space != "")
 	case value.Base64 != "":
 		val, err = xml2Base64(value.Base64)
+	case len(value.Struct) != 0 && field.Kind() == reflect.Map:
+		err = d.struct2Map(value.Struct, field)
 	case len(value.Struct) != 0:
 		if field.Kind() != reflect.Struct {
 			fault := FaultInvalidParams
-			fault.String += fmt.Sprintf("structure fields mismatch: %s != %s", field.Kind(), reflect.Struct.String())
+			fault.String += fmt.Sprintf(": structure fields mismatch: %s != %s", field.Kind(), reflect.Struct.String())
+			return fault
+		}
+		fields := d.opts.structFields(field.Type())
+		decoded := make(map[string]bool, len(value.Struct))
+		for _, m := range value.Struct {
+			f, info, ok := fields.lookup(*field, m.Name)
+			if !ok && d.opts.caseInsensitive {
+				if f, info, ok, err = fields.lookupFold(*field, m.Name); err != nil {
+					return err
+				}
+			}
+			if !ok {
+				d.extra(m)
+				continue
+			}
+			decoded[info.name] = true
+			err = d.at("."+m.Name, func() error {
+				return d.member2Field(m.Value, &f, info)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if err = fields.setDefaults(*field, decoded); err != nil {
+			return err
+		}
+	case len(value.Array) != 0 && field.Kind() == reflect.Array:
+		if len(value.Array) > field.Len() {
+			fault := FaultInvalidParams
+			fault.String += fmt.Sprintf(": array of %d items for %s", len(value.Array), field.Type())
 			return fault
 		}
-		s := value.Struct
-		for i := 0; i < len(s); i++ {
-			// Uppercase first letter for field name to deal with
-			// methods in lowercase, which cannot be used
-			field_name := uppercaseFirst(s[i].Name)
-			f := field.FieldByName(field_name)
-			err = value2Field(s[i].Value, &f)
+		for i := range value.Array {
+			item := field.Index(i)
+			err = d.at("["+strconv.Itoa(i)+"]", func() error {
+				return d.value2Field(value.Array[i], &item)
+			})
+			if err != nil {
+				return err
+			}
 		}
 	case len(value.Array) != 0:
+		if field.Kind() != reflect.Slice {
+			fault := FaultInvalidParams
+			fault.String += fmt.Sprintf(": fields type mismatch: array != %s", field.Type())
+			return fault
+		}
 		a := value.Array
 		f := *field
 		slice := reflect.MakeSlice(reflect.TypeOf(f.Interface()),
 			len(a), len(a))
 		for i := 0; i < len(a); i++ {
 			item := slice.Index(i)
-			err = value2Field(a[i], &item)
+			err = d.at("["+strconv.Itoa(i)+"]", func() error {
+				return d.value2Field(a[i], &item)
+			})
+			if err != nil {
+				return err
+			}
 		}
 		f = reflect.AppendSlice(f, slice)
 		val = f.Interface()
-	case len(value.Array) == 0:
-		val = val
-
 	default:
 		// value field is default to string, see http://en.wikipedia.org/wiki/XML-RPC#Data_types
 		// also can be <nil/>
-		if value.Raw != "<nil/>" {
-			val = value.Raw
+		text, ok := rawText(value.Raw)
+		kind := rawType(value.Raw)
+		var boolean []string
+		if !ok && d.opts.lenient {
+			boolean = rawBoolean.FindStringSubmatch(value.Raw)
+		}
+		switch {
+		case ok && text != "":
+			if d.opts.trimUntyped {
+				text = strings.TrimSpace(text)
+			}
+			val = text
+		case field.Kind() == reflect.Struct && kind == "struct":
+			// A struct without members
+			err = d.opts.structFields(field.Type()).setDefaults(*field, nil)
+		case field.Kind() == reflect.Map && kind == "struct":
+			err = d.struct2Map(nil, field)
+		case field.Kind() == reflect.Slice && kind == "array":
+			// An array without items
+			field.Set(reflect.MakeSlice(field.Type(), 0, 0))
+		case boolean != nil:
+			val = xml2Bool(strings.TrimSpace(boolean[1]))
+		case emptyValues[kind] != nil:
+			if val, err = d.emptyValue(kind); err != nil {
+				return err
+			}
+			if kind == "i8" && field.Kind() == reflect.Int {
+				val = 0
+			}
+		case kind != "" && !isType(kind):
+			if d.opts.strict {
+				fault := FaultInvalidParams
+				fault.String += fmt.Sprintf(": unknown type <%s>", kind)
+				return fault
+			}
+			d.warn(fmt.Errorf("unknown type <%s> skipped", kind))
 		}
 	}
 
+	if parseErr != nil && d.opts.collect {
+		fault := FaultInvalidParams
+		fault.String += fmt.Sprintf(": %v", parseErr)
+		return fault
+	}
+
 	if val != nil {
+		if s, ok := val.(string); ok && d.opts.lenient && field.Kind() == reflect.Bool {
+			// Some servers send booleans as strings, such as "1".
+			if b, perr := strconv.ParseBool(strings.TrimSpace(s)); perr == nil {
+				field.SetBool(b)
+				return err
+			}
+		}
+		if v := reflect.ValueOf(val); d.opts.lenient && field.Kind() == reflect.Bool && isIntKind(v.Kind()) {
+			// Some servers send booleans as integers, 0 or 1.
+			if n := v.Int(); n != 0 && n != 1 {
+				fault := FaultInvalidParams
+				fault.String += fmt.Sprintf(": integer %d for a boolean", n)
+				return fault
+			}
+			field.SetBool(v.Int() == 1)
+			return err
+		}
+		if d.opts.lenient && reflect.TypeOf(val) != field.Type() && coerce(val, *field) {
+			return err
+		}
 		if reflect.TypeOf(val) != reflect.TypeOf(field.Interface()) {
 			fault := FaultInvalidParams
 			fault.String += fmt.Sprintf(": fields type mismatch: %s != %s",
@@ -177,9 +453,452 @@ func value2Field(value value, field *reflect.Value) error {
 	return err
 }
 
+// rawBoolean matches a boolean wherever it is nested in the raw XML of
+// a value.
+var rawBoolean = regexp.MustCompile(`<(?:[\w.-]+:)?boolean>([^<]*)</(?:[\w.-]+:)?boolean>`)
+
+// coerce sets field to val, converted to its type, if val is of a type
+// of the same kind, such as a string for a named string type, or if val
+// is a number the field can hold, such as an int for a float64 field, as
+// some servers type whole numbers as integers. It reports whether field
+// was set.
+func coerce(val interface{}, field reflect.Value) bool {
+	v := reflect.ValueOf(val)
+	switch {
+	case v.Kind() == field.Kind() && v.Type().ConvertibleTo(field.Type()):
+		field.Set(v.Convert(field.Type()))
+	case isIntKind(v.Kind()) && isIntKind(field.Kind()):
+		if field.OverflowInt(v.Int()) {
+			return false
+		}
+		field.SetInt(v.Int())
+	case isIntKind(v.Kind()) && isUintKind(field.Kind()):
+		if v.Int() < 0 || field.OverflowUint(uint64(v.Int())) {
+			return false
+		}
+		field.SetUint(uint64(v.Int()))
+	case isIntKind(v.Kind()) && isFloatKind(field.Kind()):
+		field.SetFloat(float64(v.Int()))
+	case isFloatKind(v.Kind()) && isFloatKind(field.Kind()):
+		if field.OverflowFloat(v.Float()) {
+			return false
+		}
+		field.SetFloat(v.Float())
+	default:
+		return false
+	}
+	return true
+}
+
+func isIntKind(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Int64
+}
+
+func isUintKind(k reflect.Kind) bool {
+	return k >= reflect.Uint && k <= reflect.Uintptr
+}
+
+func isFloatKind(k reflect.Kind) bool {
+	return k == reflect.Float32 || k == reflect.Float64
+}
+
+// firstType returns value, keeping only its first typed element in
+// document order if it has several, such as both <int> and <string>, and
+// reporting the others to the warning handler. They are rejected in strict
+// mode.
+func (d *Decoder) firstType(value Value) (Value, error) {
+	if typeCount(value) < 2 {
+		return value, nil
+	}
+	var names []string
+	first := ""
+	decoder := xml.NewDecoder(strings.NewReader(value.Raw))
+	for {
+		offset := decoder.InputOffset()
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if err := decoder.Skip(); err != nil {
+			break
+		}
+		if !isType(start.Name.Local) {
+			continue
+		}
+		names = append(names, start.Name.Local)
+		if first == "" {
+			first = value.Raw[offset:decoder.InputOffset()]
+		}
+	}
+	if len(names) < 2 {
+		return value, nil
+	}
+	if d.opts.strict {
+		fault := FaultInvalidParams
+		fault.String += fmt.Sprintf(": value of several types <%s>", strings.Join(names, ">, <"))
+		return value, fault
+	}
+	d.warn(fmt.Errorf("value of several types <%s>, decoded as <%s>", strings.Join(names, ">, <"), names[0]))
+	var v Value
+	if err := xml.Unmarshal([]byte("<value>"+first+"</value>"), &v); err != nil {
+		return value, FaultDecode
+	}
+	return v, nil
+}
+
+// typeCount returns the number of typed fields set in value, whatever the
+// number of items of its array or members of its struct, so that values
+// of a single type are told apart without scanning Raw.
+func typeCount(value Value) int {
+	types := 0
+	for _, n := range []int{len(value.Array), len(value.Data), len(value.Struct)} {
+		if n != 0 {
+			types++
+		}
+	}
+	for _, text := range []string{value.String, value.Int, value.Int4, value.Int8, value.Double, value.Boolean, value.DateTime, value.Base64} {
+		if text != "" {
+			types++
+		}
+	}
+	return types
+}
+
+// singleValue reports whether value is neither an array, nor nil, nor
+// empty.
+func singleValue(value Value) bool {
+	switch rawType(value.Raw) {
+	case "array", "nil":
+		return false
+	case "":
+		return strings.TrimSpace(value.Raw) != ""
+	}
+	return true
+}
+
+// bareArray returns v, with the items of a <data> element sent without
+// its <array> wrapper, as some servers do, as an array.
+func bareArray(v Value) Value {
+	if len(v.Array) != 0 || !strings.Contains(v.Raw, "data") || rawType(v.Raw) != "data" {
+		return v
+	}
+	v.Array, v.Data = v.Data, nil
+	v.Raw = "<array>" + v.Raw + "</array>"
+	return v
+}
+
+// rawText returns the character data of raw, the inner XML of a value,
+// and false if raw contains elements.
+func rawText(raw string) (string, bool) {
+	var text []byte
+	decoder := xml.NewDecoder(strings.NewReader(raw))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return string(text), true
+		}
+		if err != nil {
+			return "", false
+		}
+		switch t := token.(type) {
+		case xml.CharData:
+			text = append(text, t...)
+		case xml.StartElement:
+			return "", false
+		}
+	}
+}
+
+// value2Pointer decodes value into the target of field, a pointer, which
+// is allocated if nil. A nil value sets field to nil.
+func (d *Decoder) value2Pointer(value Value, field *reflect.Value) error {
+	if rawType(value.Raw) == "nil" {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+	if field.IsNil() {
+		field.Set(reflect.New(field.Type().Elem()))
+	}
+	elem := field.Elem()
+	return d.value2Field(value, &elem)
+}
+
+// struct2Map decodes members into field, a map with string keys.
+func (d *Decoder) struct2Map(members []Member, field *reflect.Value) error {
+	t := field.Type()
+	if t.Key().Kind() != reflect.String {
+		fault := FaultInvalidParams
+		fault.String += fmt.Sprintf(": map keys must be strings: %s", t)
+		return fault
+	}
+	switch t {
+	case stringMapType:
+		return d.struct2StringMap(members, field)
+	case valuesType:
+		return d.struct2Values(members, field)
+	}
+	m := reflect.MakeMapWithSize(t, len(members))
+	for _, member := range members {
+		elem := reflect.New(t.Elem()).Elem()
+		err := d.at("."+member.Name, func() error {
+			return d.value2Field(member.Value, &elem)
+		})
+		if err != nil {
+			return err
+		}
+		m.SetMapIndex(reflect.ValueOf(member.Name).Convert(t.Key()), elem)
+	}
+	field.Set(m)
+	return nil
+}
+
+var (
+	stringMapType = reflect.TypeOf(map[string]string(nil))
+	valuesType    = reflect.TypeOf(url.Values(nil))
+)
+
+// struct2StringMap is struct2Map for the common map[string]string, setting
+// <string> members directly, without going through reflection.
+func (d *Decoder) struct2StringMap(members []Member, field *reflect.Value) error {
+	m := make(map[string]string, len(members))
+	for _, member := range members {
+		if member.Value.String != "" {
+			m[member.Name] = member.Value.String
+			continue
+		}
+		var s string
+		elem := reflect.ValueOf(&s).Elem()
+		err := d.at("."+member.Name, func() error {
+			return d.value2Field(member.Value, &elem)
+		})
+		if err != nil {
+			return err
+		}
+		m[member.Name] = s
+	}
+	field.Set(reflect.ValueOf(m))
+	return nil
+}
+
+// struct2Values sets field, a url.Values, to members, whose values are
+// arrays of strings. Members of other types are taken as a single value.
+func (d *Decoder) struct2Values(members []Member, field *reflect.Value) error {
+	values := make(url.Values, len(members))
+	for _, member := range members {
+		items := member.Value.Array
+		if len(items) == 0 && rawType(member.Value.Raw) != "array" {
+			items = []Value{member.Value}
+		}
+		strs := make([]string, len(items))
+		for i := range items {
+			elem := reflect.ValueOf(&strs[i]).Elem()
+			err := d.at("."+member.Name+"["+strconv.Itoa(i)+"]", func() error {
+				return d.value2Field(items[i], &elem)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		values[member.Name] = strs
+	}
+	field.Set(reflect.ValueOf(values))
+	return nil
+}
+
+// value2Interface converts value into the Go value it represents: structs
+// become map[string]interface{} and arrays []interface{}.
+func (d *Decoder) value2Interface(value Value) (interface{}, error) {
+	if d.opts.lenient {
+		value = bareArray(value)
+	}
+	value, err := d.firstType(value)
+	if err != nil {
+		return nil, err
+	}
+	integer, isInteger := value.Integer()
+	if isInteger {
+		if err := d.checkInteger(integer); err != nil {
+			return nil, err
+		}
+	}
+	switch {
+	case isInteger && value.Int8 == integer:
+		return strconv.ParseInt(strings.TrimSpace(integer), 10, 64)
+	case isInteger:
+		return strconv.Atoi(strings.TrimSpace(integer))
+	case value.Double != "":
+		return strconv.ParseFloat(value.Double, 64)
+	case value.String != "":
+		return value.String, nil
+	case value.Boolean != "":
+		return xml2Bool(value.Boolean), nil
+	case value.DateTime != "":
+		return d.xml2DateTime(value.DateTime, rawName(value.Raw).Space != "")
+	case value.Base64 != "":
+		return xml2Base64(value.Base64)
+	case len(value.Struct) != 0:
+		m := make(map[string]interface{}, len(value.Struct))
+		for _, member := range value.Struct {
+			v, err := d.value2Interface(member.Value)
+			if err != nil {
+				return nil, err
+			}
+			m[member.Name] = v
+		}
+		return m, nil
+	case len(value.Array) != 0:
+		a := make([]interface{}, len(value.Array))
+		for i, item := range value.Array {
+			v, err := d.value2Interface(item)
+			if err != nil {
+				return nil, err
+			}
+			a[i] = v
+		}
+		return a, nil
+	}
+	if text, ok := rawText(value.Raw); ok {
+		if d.opts.trimUntyped {
+			text = strings.TrimSpace(text)
+		}
+		return text, nil
+	}
+	kind := rawType(value.Raw)
+	switch {
+	case !isType(kind):
+		return RawValue(value.Raw), nil
+	case kind == "struct":
+		return map[string]interface{}{}, nil
+	case kind == "array":
+		return []interface{}{}, nil
+	}
+	if emptyValues[kind] != nil {
+		return d.emptyValue(kind)
+	}
+	return nil, nil
+}
+
+// emptyValues are the values of the scalar types, when their element is
+// empty, such as <string/>.
+var emptyValues = map[string]interface{}{
+	"string":           "",
+	"base64":           []byte{},
+	"int":              0,
+	"i4":               0,
+	"i8":               int64(0),
+	"double":           0.0,
+	"boolean":          false,
+	"dateTime.iso8601": time.Time{},
+}
+
+// emptyValue returns the value of an empty element of the type kind.
+// Strings and base64 data are empty; other types are zero, except in
+// strict mode, where they are rejected.
+func (d *Decoder) emptyValue(kind string) (interface{}, error) {
+	if d.opts.strict && kind != "string" && kind != "base64" {
+		fault := FaultInvalidParams
+		fault.String += fmt.Sprintf(": empty <%s>", kind)
+		return nil, fault
+	}
+	return emptyValues[kind], nil
+}
+
+// member2Field converts the value of a struct member or a param into
+// field, using the decode hook named in the field's tag if there is one.
+func (d *Decoder) member2Field(value Value, field *reflect.Value, info fieldInfo) error {
+	if layout, ok := info.opts["layout"]; ok && field.Type() == timeType {
+		if text, ok := stringText(value); ok {
+			return layout2Time(text, layout, info, field)
+		}
+	}
+	name, ok := info.opts["hook"]
+	if !ok {
+		return d.value2Field(value, field)
+	}
+	hook, ok := lookupHook(name)
+	if !ok {
+		fault := FaultApplicationError
+		fault.String += fmt.Sprintf(": unknown decode hook %q", name)
+		return fault
+	}
+	return hook(value, *field)
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	rawValueType = reflect.TypeOf(RawValue(""))
+)
+
+// RawValue holds the inner XML of a value, as received. Values of any
+// type can be decoded into RawValue destinations, and values of unknown
+// types are decoded as RawValue into interface{} destinations.
+type RawValue string
+
+// isType reports whether name is the element of an XML-RPC type.
+func isType(name string) bool {
+	return contains(parents[name], "value")
+}
+
+// stringText returns the text of value if it is a string, typed or not.
+func stringText(value Value) (string, bool) {
+	if value.String != "" {
+		return value.String, true
+	}
+	switch rawType(value.Raw) {
+	case "":
+		return rawText(value.Raw)
+	case "string":
+		return "", true
+	}
+	return "", false
+}
+
+// layout2Time parses text into field, a time.Time, with layout. Empty text
+// is the zero time for fields tagged omitempty.
+func layout2Time(text, layout string, info fieldInfo, field *reflect.Value) error {
+	text = strings.TrimSpace(text)
+	if _, omitempty := info.opts["omitempty"]; omitempty && text == "" {
+		field.Set(reflect.ValueOf(time.Time{}))
+		return nil
+	}
+	t, err := time.ParseInLocation(layout, text, time.Local)
+	if err != nil {
+		fault := FaultInvalidParams
+		fault.String += fmt.Sprintf(": %v", err)
+		return fault
+	}
+	field.Set(reflect.ValueOf(t))
+	return nil
+}
+
+// rawType returns the name of the first element in raw, the inner XML of
+// a value, which is its type when the typed fields of the value are empty.
+func rawType(raw string) string {
+	return rawName(raw).Local
+}
+
+// rawName returns the name of the first element in raw, with its prefix,
+// such as "ex", as its space.
+func rawName(raw string) xml.Name {
+	decoder := xml.NewDecoder(strings.NewReader(raw))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return xml.Name{}
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name
+		}
+	}
+}
+
 func xml2Bool(value string) bool {
 	var b bool
-	switch value {
+	switch strings.TrimSpace(value) {
 	case "1", "true", "TRUE", "True":
 		b = true
 	case "0", "false", "FALSE", "False":
@@ -188,15 +907,102 @@ func xml2Bool(value string) bool {
 	return b
 }
 
-func xml2DateTime(value string) (time.Time, error) {
+// dateTimeLayouts are the extended ISO 8601 forms accepted in lenient
+// mode and for the Apache extension <ex:dateTime.iso8601>, besides the
+// basic form of the specification.
+var dateTimeLayouts = []string{
+	"2006-01-02T15:04:05.999999999Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"20060102T15:04:05.999999999Z07:00",
+	"20060102T150405Z07:00",
+	"20060102T150405",
+}
+
+// xml2DateTime parses value in the configured location, which defaults
+// to the local one. Dates with an explicit zone keep it.
+func (d *Decoder) xml2DateTime(value string, extension bool) (time.Time, error) {
+	loc := d.opts.location
+	if loc == nil {
+		loc = time.Local
+	}
+	var (
+		t   time.Time
+		err error
+	)
+	parsed := false
+	if d.opts.lenient || extension {
+		value = strings.TrimSpace(value)
+		for _, layout := range dateTimeLayouts {
+			if t, err = time.ParseInLocation(layout, value, loc); err == nil {
+				parsed = true
+				break
+			}
+		}
+	}
+	if !parsed {
+		if t, err = xml2DateTime(value, loc); err != nil {
+			return t, err
+		}
+	}
+	if year := t.Year(); year < minYear || year > maxYear {
+		if !d.opts.lenient {
+			return time.Time{}, fmt.Errorf("dateTime %q: year %d out of range %d-%d", value, year, minYear, maxYear)
+		}
+		// Clamp the year, keeping the rest of the date.
+		if year < minYear {
+			year = minYear
+		} else {
+			year = maxYear
+		}
+		t = time.Date(year, t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	}
+	return t, nil
+}
+
+// The range of years of dateTime values, which have four digits and no era.
+const (
+	minYear = 1
+	maxYear = 9999
+)
+
+// epoch2Time converts value, if it is an integer, into the time it
+// represents as a number of seconds since January 1, 1970 UTC, in the
+// configured location.
+func (d *Decoder) epoch2Time(value Value) (time.Time, bool, error) {
+	text, ok := value.Integer()
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(text), 10, 64)
+	if err != nil {
+		return time.Time{}, true, err
+	}
+	t := time.Unix(n, 0)
+	if d.opts.location != nil {
+		t = t.In(d.opts.location)
+	}
+	return t, true, nil
+}
+
+func xml2DateTime(value string, loc *time.Location) (time.Time, error) {
 	var (
 		year, month, day     int
 		hour, minute, second int
 	)
-	_, err := fmt.Sscanf(value, "%04d%02d%02dT%02d:%02d:%02d",
-		&year, &month, &day,
+	// The year is made of the digits before the month and the day, so that
+	// years out of range are read whole rather than misread.
+	i := strings.IndexByte(value, 'T') - 4
+	if i < 1 {
+		return time.Time{}, fmt.Errorf("dateTime %q: malformed", value)
+	}
+	year, err := strconv.Atoi(value[:i])
+	if err != nil || value[0] == '-' || value[0] == '+' {
+		return time.Time{}, fmt.Errorf("dateTime %q: malformed year", value)
+	}
+	_, err = fmt.Sscanf(value[i:], "%02d%02dT%02d:%02d:%02d",
+		&month, &day,
 		&hour, &minute, &second)
-	t := time.Date(year, time.Month(month), day, hour, minute, second, 0, time.Local)
+	t := time.Date(year, time.Month(month), day, hour, minute, second, 0, loc)
 	return t, err
 }
 
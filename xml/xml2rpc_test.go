@@ -5,9 +5,15 @@
 package xml
 
 import (
+	"bytes"
+	"encoding/xml"
+	"net/url"
 	"reflect"
+	"strings"
 	"testing"
+	"testing/iotest"
 	"time"
+	"unsafe"
 )
 
 type SubStructXml2Rpc struct {
@@ -154,3 +160,1092 @@ Requiredattribute'user'notfound:
 		}
 	}
 }
+
+type StructUntypedXml2Rpc struct {
+	Str   string
+	Empty string
+	Bool  bool
+}
+
+func TestXML2RPCUntypedValues(t *testing.T) {
+	data := "<methodResponse><params><param><value>a &amp; b</value></param><param><value><string></string></value></param><param><value><wrapper><boolean>1</boolean></wrapper></value></param></params></methodResponse>"
+
+	req := new(StructUntypedXml2Rpc)
+	err := xml2RPC(data, req)
+	if err != nil {
+		t.Error("XML2RPC conversion failed", err)
+	}
+	expected_req := &StructUntypedXml2Rpc{"a & b", "", false}
+	if !reflect.DeepEqual(req, expected_req) {
+		t.Error("XML2RPC conversion failed")
+		t.Error("Expected", expected_req)
+		t.Error("Got", req)
+	}
+
+	req = new(StructUntypedXml2Rpc)
+	err = NewDecoder(Lenient()).xml2RPC(data, req)
+	if err != nil {
+		t.Error("XML2RPC conversion failed", err)
+	}
+	expected_req = &StructUntypedXml2Rpc{"a & b", "", true}
+	if !reflect.DeepEqual(req, expected_req) {
+		t.Error("XML2RPC lenient conversion failed")
+		t.Error("Expected", expected_req)
+		t.Error("Got", req)
+	}
+}
+
+type StructBase64ArrayXml2Rpc struct {
+	Blobs [][]byte
+}
+
+func TestXML2RPCBase64Array(t *testing.T) {
+	req := new(StructBase64ArrayXml2Rpc)
+	err := xml2RPC("<methodResponse><params><param><value><array><data><value><base64>Zmlyc3Q=</base64></value><value><base64>c2Vjb25k</base64></value><value><base64>dGhpcmQ=</base64></value></data></array></value></param></params></methodResponse>", req)
+	if err != nil {
+		t.Error("XML2RPC conversion failed", err)
+	}
+	expected_req := &StructBase64ArrayXml2Rpc{[][]byte{[]byte("first"), []byte("second"), []byte("third")}}
+	if !reflect.DeepEqual(req, expected_req) {
+		t.Error("XML2RPC conversion failed")
+		t.Error("Expected", expected_req)
+		t.Error("Got", req)
+	}
+}
+
+type StructDoubleTextXml2Rpc struct {
+	Amount string
+	Float  float64
+}
+
+func TestXML2RPCDoubleText(t *testing.T) {
+	req := new(StructDoubleTextXml2Rpc)
+	err := xml2RPC("<methodResponse><params><param><value><double>0.1</double></value></param><param><value><double>0.1</double></value></param></params></methodResponse>", req)
+	if err != nil {
+		t.Error("XML2RPC conversion failed", err)
+	}
+	expected_req := &StructDoubleTextXml2Rpc{"0.1", 0.1}
+	if !reflect.DeepEqual(req, expected_req) {
+		t.Error("XML2RPC conversion failed")
+		t.Error("Expected", expected_req)
+		t.Error("Got", req)
+	}
+}
+
+type StructIntFloatXml2Rpc struct {
+	Float float64
+}
+
+func TestXML2RPCLenientIntToFloat(t *testing.T) {
+	data := "<methodResponse><params><param><value><int>5</int></value></param></params></methodResponse>"
+
+	req := new(StructIntFloatXml2Rpc)
+	if err := xml2RPC(data, req); err == nil {
+		t.Error("Expected type mismatch, but got nil")
+	}
+
+	req = new(StructIntFloatXml2Rpc)
+	err := NewDecoder(Lenient()).xml2RPC(data, req)
+	if err != nil {
+		t.Error("XML2RPC lenient conversion failed", err)
+	}
+	expected_req := &StructIntFloatXml2Rpc{5}
+	if !reflect.DeepEqual(req, expected_req) {
+		t.Error("XML2RPC lenient conversion failed")
+		t.Error("Expected", expected_req)
+		t.Error("Got", req)
+	}
+}
+
+func TestDecodeRawParams(t *testing.T) {
+	params := "<params><param><value><struct><member><name>id</name><value><i4>7</i4></value></member><member><name>note</name><value><string>a &amp; b</string></value></member><member><name>empty</name><value><string></string></value></member></struct></value></param><param><value><array><data><value><boolean>1</boolean></value><value>untyped</value><value><nil/></value></data></array></value></param><param><value><ex:serializable>rO0AB</ex:serializable></value></param></params>"
+
+	raw, fault, err := DecodeRawParams(strings.NewReader("<methodResponse>" + params + "</methodResponse>"))
+	if err != nil || fault != nil {
+		t.Fatal("Expected err and fault to be nil, but got:", err, fault)
+	}
+	values := make([]Value, len(raw))
+	for i, p := range raw {
+		values[i] = p.Value
+	}
+	if xml, err := NewEncoder().params2XML(values); err != nil || xml != params {
+		t.Error("Raw params re-encoding failed")
+		t.Error("Expected", params)
+		t.Error("Got", xml)
+	}
+
+	_, fault, err = DecodeRawParams(strings.NewReader("<methodResponse><fault><value><struct><member><name>faultCode</name><value><int>4</int></value></member><member><name>faultString</name><value><string>Too many params</string></value></member></struct></value></fault></methodResponse>"))
+	if err != nil || fault == nil || fault.Code != 4 {
+		t.Errorf("expected fault 4, but got %v, %v", fault, err)
+	}
+}
+
+func TestDecodeResponseTree(t *testing.T) {
+	data := "<?xml version=\"1.0\"?><methodResponse><params><param><value><struct><member><name>id</name><value><i4>7</i4></value></member><member><name>tags</name><value><array><data><value>a</value><value><string>b</string></value></data></array></value></member></struct></value></param><param><value><double>1.5</double></value></param></params></methodResponse>"
+	tree, err := DecodeResponseTree(strings.NewReader(data))
+	if err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if tree.XMLName.Local != "methodResponse" || tree.Fault != nil || len(tree.Params) != 2 {
+		t.Fatalf("expected a response of 2 params, but got %+v", tree)
+	}
+	members := tree.Params[0].Value.Struct
+	if len(members) != 2 || members[0].Name != "id" || members[0].Value.Int4 != "7" {
+		t.Errorf("expected the id member, but got %+v", members)
+	}
+	if tags := members[1].Value.Array; len(tags) != 2 || tags[0].Raw != "a" || tags[1].String != "b" {
+		t.Errorf("expected the tags a and b, but got %+v", tags)
+	}
+	if tree.Params[1].Value.Double != "1.5" {
+		t.Errorf("expected 1.5, but got %q", tree.Params[1].Value.Double)
+	}
+
+	data = "<methodResponse><fault><value><struct><member><name>faultCode</name><value><int>4</int></value></member><member><name>faultString</name><value><string>Too many params</string></value></member></struct></value></fault></methodResponse>"
+	tree, err = DecodeResponseTree(strings.NewReader(data))
+	if err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if tree.Fault == nil || len(tree.Params) != 0 {
+		t.Fatalf("expected a fault, but got %+v", tree)
+	}
+	if code, _ := tree.Fault.Struct[0].Value.Integer(); code != "4" || tree.Fault.Struct[1].Value.String != "Too many params" {
+		t.Errorf("expected fault 4, but got %+v", tree.Fault.Struct)
+	}
+
+	if _, err := DecodeResponseTree(strings.NewReader("<methodResponse><params>")); err == nil {
+		t.Error("expected error for a truncated response, but got nil")
+	}
+}
+
+type StructUnknownTypeXml2Rpc struct {
+	Name   string
+	Object string
+	Raw    RawValue
+	Any    interface{}
+}
+
+func TestXML2RPCUnknownType(t *testing.T) {
+	data := "<methodResponse xmlns:ex=\"http://ws.apache.org/xmlrpc/namespaces/extensions\"><params><param><value><string>a</string></value></param><param><value><ex:serializable>rO0AB</ex:serializable></value></param><param><value><ex:serializable>rO0AC</ex:serializable></value></param><param><value><ex:serializable>rO0AD</ex:serializable></value></param></params></methodResponse>"
+
+	var warnings []error
+	req := new(StructUnknownTypeXml2Rpc)
+	err := NewDecoder(Lenient(), WithWarningHandler(func(err error) {
+		warnings = append(warnings, err)
+	})).xml2RPC(data, req)
+	if err != nil {
+		t.Error("XML2RPC conversion failed", err)
+	}
+	expected_req := &StructUnknownTypeXml2Rpc{"a", "", "<ex:serializable>rO0AC</ex:serializable>", RawValue("<ex:serializable>rO0AD</ex:serializable>")}
+	if !reflect.DeepEqual(req, expected_req) {
+		t.Error("XML2RPC conversion failed")
+		t.Error("Expected", expected_req)
+		t.Error("Got", req)
+	}
+	if len(warnings) != 1 || warnings[0].Error() != "params[1]: unknown type <serializable> skipped" {
+		t.Errorf("wrong warnings: %v", warnings)
+	}
+
+	err = NewDecoder(Strict()).xml2RPC(data, new(StructUnknownTypeXml2Rpc))
+	if fault, ok := err.(Fault); !ok || !strings.Contains(fault.String, "<serializable>") {
+		t.Errorf("expected fault naming <serializable>, but got %v", err)
+	}
+}
+
+func TestXML2RPCExtraParams(t *testing.T) {
+	tests := []struct {
+		data     string
+		warnings []string
+	}{
+		{
+			"<methodResponse><params><param><value><int>1</int></value></param><param><value><string>diagnostic</string></value></param></params></methodResponse>",
+			[]string{"params[1]: extra param ignored"},
+		},
+		{
+			"<methodResponse><params><param><value><int>1</int></value></param><param><value>a</value></param><param><value>b</value></param><param><value>c</value></param></params></methodResponse>",
+			[]string{"params[1]: extra param ignored", "params[2]: extra param ignored", "params[3]: extra param ignored"},
+		},
+	}
+	for _, test := range tests {
+		if err := xml2RPC(test.data, new(struct{ N int })); err == nil {
+			t.Error("expected error without AllowExtraParams, but got nil")
+		}
+
+		for _, stream := range []bool{false, true} {
+			var warnings []string
+			d := NewDecoder(AllowExtraParams(), WithWarningHandler(func(err error) {
+				warnings = append(warnings, err.Error())
+			}))
+			req := new(struct{ N int })
+			var err error
+			if stream {
+				err = d.decodeStream(strings.NewReader(test.data), req)
+			} else {
+				err = d.xml2RPC(test.data, req)
+			}
+			if err != nil {
+				t.Error("XML2RPC conversion failed", err)
+			}
+			if req.N != 1 {
+				t.Errorf("expected 1, but got %d", req.N)
+			}
+			if !reflect.DeepEqual(warnings, test.warnings) {
+				t.Errorf("stream %v: expected warnings %v, but got %v", stream, test.warnings, warnings)
+			}
+		}
+	}
+}
+
+func TestXML2RPCBoolArray(t *testing.T) {
+	req := new(struct{ Flags []bool })
+	err := xml2RPC("<methodResponse><params><param><value><array><data><value><boolean>1</boolean></value><value><boolean>true</boolean></value><value><boolean>0</boolean></value><value><boolean>false</boolean></value></data></array></value></param></params></methodResponse>", req)
+	if err != nil {
+		t.Error("XML2RPC conversion failed", err)
+	}
+	expected := []bool{true, true, false, false}
+	if !reflect.DeepEqual(req.Flags, expected) {
+		t.Errorf("expected %v, but got %v", expected, req.Flags)
+	}
+
+	err = xml2RPC("<methodResponse><params><param><value><array><data><value><boolean>1</boolean></value><value><int>3</int></value><value><boolean>0</boolean></value></data></array></value></param></params></methodResponse>", req)
+	if err == nil {
+		t.Error("expected error for an int in a boolean array, but got nil")
+	}
+}
+
+func TestXML2RPCEmptyElements(t *testing.T) {
+	tests := []struct {
+		value    string
+		reply    interface{}
+		expected interface{}
+	}{
+		{"<string/>", &struct{ V string }{"x"}, ""},
+		{"<string></string>", &struct{ V string }{"x"}, ""},
+		{"<base64/>", &struct{ V []byte }{}, []byte{}},
+		{"<int/>", &struct{ V int }{1}, 0},
+		{"<i4/>", &struct{ V int }{1}, 0},
+		{"<i8/>", &struct{ V int64 }{1}, int64(0)},
+		{"<ex:i8/>", &struct{ V int }{1}, 0},
+		{"<double/>", &struct{ V float64 }{1}, 0.0},
+		{"<boolean/>", &struct{ V bool }{true}, false},
+		{"<dateTime.iso8601/>", &struct{ V time.Time }{time.Now()}, time.Time{}},
+	}
+	for _, test := range tests {
+		data := "<methodResponse><params><param><value>" + test.value + "</value></param></params></methodResponse>"
+
+		if err := xml2RPC(data, test.reply); err != nil {
+			t.Errorf("%s: expected err to be nil, but got %v", test.value, err)
+		}
+		if got := reflect.ValueOf(test.reply).Elem().Field(0).Interface(); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%s: expected %#v, but got %#v", test.value, test.expected, got)
+		}
+
+		var untyped struct{ V interface{} }
+		if err := xml2RPC(data, &untyped); err != nil {
+			t.Errorf("%s: expected err to be nil, but got %v", test.value, err)
+		}
+		if expected := emptyValues[rawType(test.value)]; !reflect.DeepEqual(untyped.V, expected) {
+			t.Errorf("%s: expected %#v, but got %#v", test.value, expected, untyped.V)
+		}
+
+		err := NewDecoder(Strict()).xml2RPC(data, test.reply)
+		switch rawType(test.value) {
+		case "string", "base64":
+			if err != nil {
+				t.Errorf("%s: expected err to be nil in strict mode, but got %v", test.value, err)
+			}
+		default:
+			if err == nil {
+				t.Errorf("%s: expected error in strict mode, but got nil", test.value)
+			}
+		}
+	}
+
+	if err := xml2RPC("<methodResponse><params><param><value><int/></value></param></params></methodResponse>", new(struct{ V string })); err == nil {
+		t.Error("expected error decoding <int/> into a string, but got nil")
+	}
+}
+
+func TestXML2RPCDateTimeYears(t *testing.T) {
+	tests := []struct {
+		value   string
+		year    int
+		lenient int
+	}{
+		{"00010101T00:00:00", 1, 1},
+		{"99991231T23:59:59", 9999, 9999},
+		{"00000101T00:00:00", 0, 1},
+		{"100000101T00:00:00", 0, 9999},
+	}
+	for _, test := range tests {
+		data := "<methodResponse><params><param><value><dateTime.iso8601>" + test.value + "</dateTime.iso8601></value></param></params></methodResponse>"
+
+		req := new(struct{ T time.Time })
+		err := NewDecoder(WithLocation(time.UTC)).xml2RPC(data, req)
+		if test.year == 0 {
+			if err == nil || !strings.Contains(err.Error(), "out of range") {
+				t.Errorf("%s: expected out of range error, but got %v", test.value, err)
+			}
+		} else if err != nil || req.T.Year() != test.year {
+			t.Errorf("%s: expected year %d, but got %d, %v", test.value, test.year, req.T.Year(), err)
+		}
+
+		req = new(struct{ T time.Time })
+		err = NewDecoder(Lenient(), WithLocation(time.UTC)).xml2RPC(data, req)
+		if err != nil || req.T.Year() != test.lenient {
+			t.Errorf("%s: expected year %d in lenient mode, but got %d, %v", test.value, test.lenient, req.T.Year(), err)
+		}
+	}
+}
+
+func TestTime2XMLYears(t *testing.T) {
+	tests := []struct {
+		t        time.Time
+		expected string
+	}{
+		{time.Time{}, "<dateTime.iso8601>00010101T00:00:00</dateTime.iso8601>"},
+		{time.Date(1, 2, 3, 4, 5, 6, 0, time.UTC), "<dateTime.iso8601>00010203T04:05:06</dateTime.iso8601>"},
+		{time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC), "<dateTime.iso8601>99991231T23:59:59</dateTime.iso8601>"},
+	}
+	for _, test := range tests {
+		xml, err := time2XML(test.t)
+		if err != nil || xml != test.expected {
+			t.Errorf("%v: expected %s, but got %s, %v", test.t, test.expected, xml, err)
+		}
+	}
+
+	for _, tm := range []time.Time{
+		time.Date(0, 12, 31, 0, 0, 0, 0, time.UTC),
+		time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC),
+	} {
+		if _, err := rpcRequest2XML("Some.Method", &struct{ T time.Time }{tm}); err == nil {
+			t.Errorf("%v: expected error, but got nil", tm)
+		}
+	}
+}
+
+func TestEmptyCompositesRoundTrip(t *testing.T) {
+	data := "<methodResponse><params><param><value><struct><member><name>total</name><value><int>0</int></value></member><member><name>results</name><value><array><data/></array></value></member><member><name>options</name><value><struct/></value></member><member><name>nested</name><value><array><data><value><struct></struct></value><value><array><data></data></array></value></data></array></value></member></struct></value></param></params></methodResponse>"
+
+	// As a proxy would, decoding into interface{} and encoding back
+	var reply struct{ Result interface{} }
+	if err := DecodeClientResponse(strings.NewReader(data), &reply); err != nil {
+		t.Error("Expected err to be nil, but got:", err)
+	}
+	result, _ := reply.Result.(map[string]interface{})
+	if results, ok := result["results"].([]interface{}); !ok || results == nil || len(results) != 0 {
+		t.Errorf("expected empty []interface{}, but got %#v", result["results"])
+	}
+	if options, ok := result["options"].(map[string]interface{}); !ok || options == nil || len(options) != 0 {
+		t.Errorf("expected empty map[string]interface{}, but got %#v", result["options"])
+	}
+	buf, err := EncodeServerResponse(&reply)
+	if err != nil {
+		t.Error("Expected err to be nil, but got:", err)
+	}
+	if equal, diff, err := Equal(strings.NewReader(data), bytes.NewReader(buf)); err != nil || !equal {
+		t.Errorf("expected equal documents, but got %v:\n%s", err, diff)
+	}
+
+	// Forwarding the raw params
+	params, _, err := DecodeRawParams(strings.NewReader(data))
+	if err != nil {
+		t.Error("Expected err to be nil, but got:", err)
+	}
+	var raw bytes.Buffer
+	if err := EncodeParamsRaw(&raw, "", params); err != nil {
+		t.Error("Expected err to be nil, but got:", err)
+	}
+	if equal, diff, err := Equal(strings.NewReader(data), &raw); err != nil || !equal {
+		t.Errorf("expected equal documents, but got %v:\n%s", err, diff)
+	}
+
+	// Into typed fields
+	var typed struct {
+		Result struct {
+			Total   int               `xmlrpc:"total"`
+			Results []string          `xmlrpc:"results"`
+			Options map[string]string `xmlrpc:"options"`
+			Nested  []interface{}     `xmlrpc:"nested"`
+		}
+	}
+	if err := DecodeClientResponse(strings.NewReader(data), &typed); err != nil {
+		t.Error("Expected err to be nil, but got:", err)
+	}
+	if typed.Result.Results == nil || typed.Result.Options == nil {
+		t.Errorf("expected non-nil empty slice and map, but got %#v", typed.Result)
+	}
+	buf, err = EncodeServerResponse(&typed)
+	if err != nil {
+		t.Error("Expected err to be nil, but got:", err)
+	}
+	if equal, diff, err := Equal(strings.NewReader(data), bytes.NewReader(buf)); err != nil || !equal {
+		t.Errorf("expected equal documents, but got %v:\n%s", err, diff)
+	}
+}
+
+func TestXML2RPCStructToMap(t *testing.T) {
+	req := new(struct{ Counts map[string]int })
+	err := xml2RPC("<methodResponse><params><param><value><struct><member><name>a</name><value><int>1</int></value></member><member><name>b</name><value><int>2</int></value></member></struct></value></param></params></methodResponse>", req)
+	if err != nil {
+		t.Error("XML2RPC conversion failed", err)
+	}
+	expected := map[string]int{"a": 1, "b": 2}
+	if !reflect.DeepEqual(req.Counts, expected) {
+		t.Errorf("expected %v, but got %v", expected, req.Counts)
+	}
+}
+
+func TestXML2RPCUnsupportedKinds(t *testing.T) {
+	values := []string{"<int>1</int>", "<string>a</string>", "<struct><member><name>a</name><value>b</value></member></struct>", "<array><data><value>a</value></data></array>", "<nil/>"}
+	replies := []interface{}{
+		new(struct{ C chan int }),
+		new(struct{ F func() }),
+		new(struct{ C complex64 }),
+		new(struct{ C complex128 }),
+		new(struct{ P unsafe.Pointer }),
+	}
+	for _, reply := range replies {
+		for _, value := range values {
+			data := "<methodResponse><params><param><value>" + value + "</value></param></params></methodResponse>"
+			field := reflect.TypeOf(reply).Elem().Field(0).Type.String()
+			err := xml2RPC(data, reply)
+			if err == nil || !strings.Contains(err.Error(), "unsupported field type "+field) {
+				t.Errorf("%s into %s: expected unsupported field type error, but got %v", value, field, err)
+			}
+		}
+	}
+}
+
+func TestXML2RPCLenientCDATA(t *testing.T) {
+	data := "<methodResponse><params><param><Value><String><![CDATA[<Int>5</Int>]]></String></Value></param><!-- <BOOLEAN>1</BOOLEAN> --><param><value><STRING>a <![CDATA[</Struct>]]></STRING></value></param></params></methodResponse>"
+	expected := []interface{}{"<Int>5</Int>", "a </Struct>"}
+
+	var params []interface{}
+	if err := NewDecoder(Lenient()).xml2RPC(data, &params); err != nil {
+		t.Error("XML2RPC conversion failed", err)
+	}
+	if !reflect.DeepEqual(params, expected) {
+		t.Errorf("expected %q, but got %q", expected, params)
+	}
+
+	params = nil
+	if err := NewDecoder(Lenient(), WithStreamThreshold(0)).DecodeAuto(iotest.OneByteReader(strings.NewReader(data)), -1, &params); err != nil {
+		t.Error("XML2RPC conversion failed", err)
+	}
+	if !reflect.DeepEqual(params, expected) {
+		t.Errorf("streamed: expected %q, but got %q", expected, params)
+	}
+}
+
+func TestXML2RPCArrayMismatch(t *testing.T) {
+	data := "<methodResponse><params><param><value><struct><member><name>Items</name><value><array><data><value><int>1</int></value><value><int>2</int></value></data></array></value></member></struct></value></param></params></methodResponse>"
+	replies := []interface{}{
+		new(struct{ S struct{ Items int } }),
+		new(struct{ S struct{ Items string } }),
+		new(struct {
+			S struct{ Items struct{ A int } }
+		}),
+		new(struct{ S struct{ Items [1]int } }),
+	}
+	for _, reply := range replies {
+		field := reflect.TypeOf(reply).Elem().Field(0).Type.Field(0).Type
+		err := NewDecoder(CollectErrors()).xml2RPC(data, reply)
+		errs, ok := err.(*DecodeErrors)
+		if !ok || len(errs.Errors) != 1 || errs.Errors[0].Path != "params[0].Items" {
+			t.Errorf("%s: expected a mismatch at params[0].Items, but got %v", field, err)
+		}
+		if err := xml2RPC(data, reply); err == nil {
+			t.Errorf("%s: expected error, but got nil", field)
+		}
+	}
+
+	reply := new(struct{ S struct{ Items [3]int } })
+	if err := xml2RPC(data, reply); err != nil {
+		t.Error("Expected err to be nil, but got:", err)
+	}
+	if reply.S.Items != [3]int{1, 2, 0} {
+		t.Errorf("expected [1 2 0], but got %v", reply.S.Items)
+	}
+}
+
+func TestXML2RPCMixedCaseNames(t *testing.T) {
+	data := "<methodResponse><params><param><value><Int>42</Int></value></param><param><value><STRING>text</STRING></value></param><param><value><Boolean>1</Boolean></value></param><param><value><Struct><member><name>when</name><value><DATETIME.ISO8601>20130101T10:11:12</DATETIME.ISO8601></value></member></Struct></value></param></params></methodResponse>"
+	type When struct {
+		When time.Time `xmlrpc:"when"`
+	}
+	expected := struct {
+		N    int
+		S    string
+		B    bool
+		When When
+	}{42, "text", true, When{time.Date(2013, 1, 1, 10, 11, 12, 0, time.UTC)}}
+
+	for _, stream := range []bool{false, true} {
+		req := new(struct {
+			N    int
+			S    string
+			B    bool
+			When When
+		})
+		d := NewDecoder(Lenient(), WithLocation(time.UTC))
+		var err error
+		if stream {
+			err = d.decodeStream(iotest.OneByteReader(strings.NewReader(data)), req)
+		} else {
+			err = d.xml2RPC(data, req)
+		}
+		if err != nil {
+			t.Errorf("stream %v: XML2RPC conversion failed: %v", stream, err)
+		}
+		if *req != expected {
+			t.Errorf("stream %v: expected %+v, but got %+v", stream, expected, *req)
+		}
+	}
+
+	req := new(struct {
+		N    int
+		S    string
+		B    bool
+		When When
+	})
+	xml2RPC(data, req)
+	if req.N != 0 || req.S != "" || req.B {
+		t.Errorf("expected mixed-case names to be ignored by default, but got %+v", *req)
+	}
+}
+
+func TestXML2RPCNestedShapes(t *testing.T) {
+	type Geo struct {
+		Lat float64 `xmlrpc:"lat"`
+		Lon float64 `xmlrpc:"lon"`
+	}
+	type Office struct {
+		City string `xmlrpc:"city"`
+		Geo  Geo    `xmlrpc:"geo"`
+	}
+	type Company struct {
+		Name    string   `xmlrpc:"name"`
+		Offices []Office `xmlrpc:"offices"`
+		Tags    [][]int  `xmlrpc:"tags"`
+	}
+	office := func(city string, lat, lon string) string {
+		return "<value><struct><member><name>city</name><value><string>" + city + "</string></value></member><member><name>geo</name><value><struct><member><name>lat</name><value><double>" + lat + "</double></value></member><member><name>lon</name><value><double>" + lon + "</double></value></member></struct></value></member></struct></value>"
+	}
+	data := "<methodResponse><params><param><value><struct><member><name>name</name><value><string>Acme</string></value></member><member><name>offices</name><value><array><data>" +
+		office("Kyiv", "50.45", "30.52") + office("Lisbon", "38.72", "-9.14") +
+		"</data></array></value></member><member><name>tags</name><value><array><data><value><array><data><value><int>1</int></value><value><int>2</int></value></data></array></value><value><array><data/></array></value></data></array></value></member></struct></value></param></params></methodResponse>"
+
+	req := new(struct{ Company Company })
+	if err := xml2RPC(data, req); err != nil {
+		t.Error("XML2RPC conversion failed", err)
+	}
+	expected := Company{
+		Name: "Acme",
+		Offices: []Office{
+			{"Kyiv", Geo{50.45, 30.52}},
+			{"Lisbon", Geo{38.72, -9.14}},
+		},
+		Tags: [][]int{{1, 2}, {}},
+	}
+	if !reflect.DeepEqual(req.Company, expected) {
+		t.Errorf("expected %+v, but got %+v", expected, req.Company)
+	}
+
+	pointers := new(struct {
+		Company struct {
+			Offices []*struct {
+				City string `xmlrpc:"city"`
+				Geo  *Geo   `xmlrpc:"geo"`
+			} `xmlrpc:"offices"`
+		}
+	})
+	if err := xml2RPC(data, pointers); err != nil {
+		t.Error("XML2RPC conversion failed", err)
+	}
+	if offices := pointers.Company.Offices; len(offices) != 2 || offices[1].City != "Lisbon" || *offices[1].Geo != (Geo{38.72, -9.14}) {
+		t.Errorf("expected Lisbon at 38.72,-9.14, but got %+v", offices)
+	}
+
+	untyped := new(struct{ Company interface{} })
+	if err := xml2RPC(data, untyped); err != nil {
+		t.Error("XML2RPC conversion failed", err)
+	}
+	company, _ := untyped.Company.(map[string]interface{})
+	offices, _ := company["offices"].([]interface{})
+	if len(offices) != 2 {
+		t.Fatalf("expected 2 offices, but got %#v", company["offices"])
+	}
+	second, _ := offices[1].(map[string]interface{})
+	geo, _ := second["geo"].(map[string]interface{})
+	if second["city"] != "Lisbon" || geo["lat"] != 38.72 || geo["lon"] != -9.14 {
+		t.Errorf("expected Lisbon at 38.72,-9.14, but got %#v", second)
+	}
+}
+
+func TestXML2RPCPointers(t *testing.T) {
+	n := 1
+	req := &struct {
+		N *int
+		S *string
+	}{N: &n}
+	err := xml2RPC("<methodResponse><params><param><value><int>42</int></value></param><param><value><string>text</string></value></param></params></methodResponse>", req)
+	if err != nil {
+		t.Error("XML2RPC conversion failed", err)
+	}
+	if req.N != &n || n != 42 || req.S == nil || *req.S != "text" {
+		t.Errorf("expected 42 in place and text, but got %v, %v", req.N, req.S)
+	}
+
+	err = xml2RPC("<methodResponse><params><param><value><nil/></value></param><param><value><ex:nil/></value></param></params></methodResponse>", req)
+	if err != nil {
+		t.Error("XML2RPC conversion failed", err)
+	}
+	if req.N != nil || req.S != nil {
+		t.Errorf("expected nil pointers, but got %v, %v", req.N, req.S)
+	}
+}
+
+func TestXML2RPCEpochTimes(t *testing.T) {
+	kyiv := time.FixedZone("EET", 2*60*60)
+	data := "<methodResponse><params><param><value><int>1357034400</int></value></param><param><value><i8>1357034400</i8></value></param><param><value><dateTime.iso8601>20130101T12:00:00</dateTime.iso8601></value></param></params></methodResponse>"
+	expected := time.Date(2013, 1, 1, 12, 0, 0, 0, kyiv)
+
+	req := new(struct{ A, B, C time.Time })
+	if err := NewDecoder(EpochTimes(), WithLocation(kyiv)).xml2RPC(data, req); err != nil {
+		t.Error("XML2RPC conversion failed", err)
+	}
+	for _, got := range []time.Time{req.A, req.B, req.C} {
+		if !got.Equal(expected) || got.Location() != kyiv {
+			t.Errorf("expected %v in EET, but got %v", expected, got)
+		}
+	}
+
+	if err := xml2RPC(data, req); err == nil {
+		t.Error("expected error decoding an int into time.Time without EpochTimes, but got nil")
+	}
+}
+
+func TestXML2RPCExtensionDateTime(t *testing.T) {
+	data := "<methodResponse><params><param><value><ex:dateTime.iso8601>2023-01-01T12:00:00+02:00</ex:dateTime.iso8601></value></param><param><value><ex:dateTime.iso8601>2023-01-01T12:00:00+02:00</ex:dateTime.iso8601></value></param></params></methodResponse>"
+	expected := time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	req := new(struct {
+		T time.Time
+		I interface{}
+	})
+	if err := xml2RPC(data, req); err != nil {
+		t.Error("XML2RPC conversion failed", err)
+	}
+	if !req.T.Equal(expected) {
+		t.Errorf("expected %v, but got %v", expected, req.T)
+	}
+	if got, ok := req.I.(time.Time); !ok || !got.Equal(expected) {
+		t.Errorf("expected %v, but got %v", expected, req.I)
+	}
+
+	data = "<methodResponse><params><param><value><dateTime.iso8601>2023-01-01T12:00:00+02:00</dateTime.iso8601></value></param></params></methodResponse>"
+	if err := xml2RPC(data, &struct{ T time.Time }{}); err == nil {
+		t.Error("expected error decoding an extended dateTime in strict mode, but got nil")
+	}
+}
+
+func TestXML2RPCStringMap(t *testing.T) {
+	data := "<methodResponse><params><param><value><struct><member><name>name</name><value><string>Ivan</string></value></member><member><name>city</name><value>Kyiv</value></member><member><name>empty</name><value><string></string></value></member><member><name>id</name><value><int>42</int></value></member></struct></value></param></params></methodResponse>"
+	req := new(struct{ M map[string]string })
+	err := NewDecoder(PreserveNumericText()).xml2RPC(data, req)
+	if err != nil {
+		t.Error("XML2RPC conversion failed", err)
+	}
+	expected := map[string]string{"name": "Ivan", "city": "Kyiv", "empty": "", "id": "42"}
+	if !reflect.DeepEqual(req.M, expected) {
+		t.Errorf("expected %v, but got %v", expected, req.M)
+	}
+}
+
+func TestXML2RPCURLValues(t *testing.T) {
+	data := "<methodResponse><params><param><value><struct><member><name>tag</name><value><array><data><value><string>go</string></value><value>xml</value></data></array></value></member><member><name>page</name><value><string>2</string></value></member><member><name>none</name><value><array><data></data></array></value></member></struct></value></param></params></methodResponse>"
+	req := new(struct{ V url.Values })
+	if err := xml2RPC(data, req); err != nil {
+		t.Error("XML2RPC conversion failed", err)
+	}
+	expected := url.Values{"tag": {"go", "xml"}, "page": {"2"}, "none": {}}
+	if !reflect.DeepEqual(req.V, expected) {
+		t.Errorf("expected %v, but got %v", expected, req.V)
+	}
+	if req.V.Encode() != "page=2&tag=go&tag=xml" {
+		t.Errorf("expected page=2&tag=go&tag=xml, but got %s", req.V.Encode())
+	}
+}
+
+func TestValueInteger(t *testing.T) {
+	tests := []struct {
+		raw      string
+		expected string
+		ok       bool
+	}{
+		{"<int>1</int>", "1", true},
+		{"<i4>-4</i4>", "-4", true},
+		{"<i8>8589934592</i8>", "8589934592", true},
+		{"<ex:i8>8</ex:i8>", "8", true},
+		{"<double>1</double>", "", false},
+		{"<string>1</string>", "", false},
+		{"1", "", false},
+	}
+	for _, test := range tests {
+		var v Value
+		if err := xml.Unmarshal([]byte("<value>"+test.raw+"</value>"), &v); err != nil {
+			t.Fatal(err)
+		}
+		text, ok := v.Integer()
+		if text != test.expected || ok != test.ok {
+			t.Errorf("%s: expected %q, %v, but got %q, %v", test.raw, test.expected, test.ok, text, ok)
+		}
+	}
+}
+
+func TestXML2RPCBareData(t *testing.T) {
+	data := "<methodResponse><params><param><value><data><value><int>1</int></value><value><int>2</int></value></data></value></param><param><value><data><value><string>a</string></value><value><data></data></value></data></value></param><param><value><data/></value></param></params></methodResponse>"
+	req := new(struct {
+		A []int
+		B []interface{}
+		C []string
+	})
+	if err := NewDecoder(Lenient()).xml2RPC(data, req); err != nil {
+		t.Error("XML2RPC conversion failed", err)
+	}
+	if !reflect.DeepEqual(req.A, []int{1, 2}) {
+		t.Errorf("expected [1 2], but got %v", req.A)
+	}
+	if !reflect.DeepEqual(req.B, []interface{}{"a", []interface{}{}}) {
+		t.Errorf("expected [a []], but got %#v", req.B)
+	}
+	if req.C == nil || len(req.C) != 0 {
+		t.Errorf("expected an empty slice, but got %#v", req.C)
+	}
+
+	strict := new(struct{ A, B, C []int })
+	if err := xml2RPC(data, strict); err != nil || strict.A != nil {
+		t.Errorf("expected <data> without <array> to be skipped, but got %v, %v", strict.A, err)
+	}
+}
+
+func TestDecodeClientResponseRawXML(t *testing.T) {
+	data := "<?xml version=\"1.0\"?>\n<methodResponse>\n  <params>\n    <param><value><string>first</string></value></param>\n    <param><value><int>2</int></value></param>\n  </params>\n</methodResponse>\n"
+
+	var reply struct {
+		First string
+		Raw   string `xmlrpc:",rawxml"`
+		N     int
+	}
+	if err := DecodeClientResponse(strings.NewReader(data), &reply); err != nil {
+		t.Error("Expected err to be nil, but got:", err)
+	}
+	if reply.First != "first" || reply.N != 2 {
+		t.Errorf("expected first and 2, but got %q and %d", reply.First, reply.N)
+	}
+	if reply.Raw != data {
+		t.Errorf("expected %q, but got %q", data, reply.Raw)
+	}
+
+	var bytesReply struct {
+		First string
+		N     int
+		Raw   []byte `xmlrpc:",rawxml"`
+	}
+	// Responses aren't streamed when they are kept whole
+	if err := NewDecoder(WithStreamThreshold(10)).decodeAuto(strings.NewReader(data), -1, &bytesReply); err != nil {
+		t.Error("Expected err to be nil, but got:", err)
+	}
+	if string(bytesReply.Raw) != data {
+		t.Errorf("expected %q, but got %q", data, bytesReply.Raw)
+	}
+
+	var wrongType struct {
+		First string
+		N     int
+		Raw   int `xmlrpc:",rawxml"`
+	}
+	if err := DecodeClientResponse(strings.NewReader(data), &wrongType); err == nil {
+		t.Error("expected error for a rawxml field of type int, but got nil")
+	}
+}
+
+func TestXML2RPCSingleValueSlice(t *testing.T) {
+	data := "<methodResponse><params><param><value><int>42</int></value></param><param><value>text</value></param><param><value><struct><member><name>Foo</name><value><int>1</int></value></member></struct></value></param><param><value><nil/></value></param><param><value><base64>eW91</base64></value></param></params></methodResponse>"
+	req := new(struct {
+		Ints    []int
+		Strings []string
+		Structs []struct{ Foo int }
+		Nil     []int
+		Bytes   []byte
+	})
+	if err := NewDecoder(Lenient()).xml2RPC(data, req); err != nil {
+		t.Error("XML2RPC conversion failed", err)
+	}
+	if !reflect.DeepEqual(req.Ints, []int{42}) {
+		t.Errorf("expected [42], but got %v", req.Ints)
+	}
+	if !reflect.DeepEqual(req.Strings, []string{"text"}) {
+		t.Errorf("expected [text], but got %v", req.Strings)
+	}
+	if len(req.Structs) != 1 || req.Structs[0].Foo != 1 {
+		t.Errorf("expected [{1}], but got %v", req.Structs)
+	}
+	if req.Nil != nil {
+		t.Errorf("expected nil, but got %v", req.Nil)
+	}
+	if string(req.Bytes) != "you" {
+		t.Errorf("expected you, but got %q", req.Bytes)
+	}
+
+	if err := xml2RPC(data, new(struct {
+		Ints    []int
+		Strings []string
+		Structs []struct{ Foo int }
+		Nil     []int
+		Bytes   []byte
+	})); err == nil {
+		t.Error("expected error decoding an int into []int in strict mode, but got nil")
+	}
+}
+
+type colorXml2Rpc string
+
+func TestXML2RPCLenientArrayCoercion(t *testing.T) {
+	data := "<methodResponse><params><param><value><array><data><value><int>1</int></value><value><double>2.5</double></value><value><i8>3</i8></value></data></array></value></param><param><value><array><data><value><string>red</string></value><value>blue</value></data></array></value></param><param><value><array><data><value><int>-1</int></value><value><int>200</int></value></data></array></value></param></params></methodResponse>"
+	req := new(struct {
+		Floats []float64
+		Colors []colorXml2Rpc
+		Small  []int16
+	})
+	if err := NewDecoder(Lenient()).xml2RPC(data, req); err != nil {
+		t.Error("XML2RPC conversion failed", err)
+	}
+	if !reflect.DeepEqual(req.Floats, []float64{1, 2.5, 3}) {
+		t.Errorf("expected [1 2.5 3], but got %v", req.Floats)
+	}
+	if !reflect.DeepEqual(req.Colors, []colorXml2Rpc{"red", "blue"}) {
+		t.Errorf("expected [red blue], but got %v", req.Colors)
+	}
+	if !reflect.DeepEqual(req.Small, []int16{-1, 200}) {
+		t.Errorf("expected [-1 200], but got %v", req.Small)
+	}
+
+	overflow := "<methodResponse><params><param><value><array><data><value><int>70000</int></value></data></array></value></param></params></methodResponse>"
+	if err := NewDecoder(Lenient()).xml2RPC(overflow, new(struct{ U []uint16 })); err == nil {
+		t.Error("expected error decoding 70000 into a uint16, but got nil")
+	}
+	if err := xml2RPC(data, new(struct {
+		Floats []float64
+		Colors []colorXml2Rpc
+		Small  []int16
+	})); err == nil {
+		t.Error("expected error decoding mixed numbers into []float64 in strict mode, but got nil")
+	}
+}
+
+func TestSetDefaults(t *testing.T) {
+	kyiv := time.FixedZone("EET", 2*60*60)
+	SetDefaults(WithLocation(kyiv))
+	defer SetDefaults()
+
+	data := "<methodResponse><params><param><value><dateTime.iso8601>20130101T12:00:00</dateTime.iso8601></value></param></params></methodResponse>"
+	expected := time.Date(2013, 1, 1, 12, 0, 0, 0, kyiv)
+	req := new(struct{ T time.Time })
+	if err := DecodeClientResponse(strings.NewReader(data), req); err != nil {
+		t.Error("XML2RPC conversion failed", err)
+	}
+	if !req.T.Equal(expected) || req.T.Location() != kyiv {
+		t.Errorf("expected %v in EET, but got %v", expected, req.T)
+	}
+
+	if err := NewDecoder().xml2RPC(data, req); err != nil {
+		t.Error("XML2RPC conversion failed", err)
+	}
+	if req.T.Location() == kyiv {
+		t.Error("expected a decoder created without options not to use the defaults")
+	}
+}
+
+func TestXML2RPCWhitespaceBeforeType(t *testing.T) {
+	data := "<methodResponse><params><param><value>  <int>5</int></value></param><param><value>\n  <string></string>\n</value></param><param><value> <boolean>1</boolean> </value></param><param><value>\n\t<array><data></data></array>\n</value></param><param><value> <nil/> </value></param><param><value>  <i4>7</i4>  </value></param></params></methodResponse>"
+	req := new(struct {
+		I int
+		S string
+		B bool
+		A []int
+		P *int
+		X interface{}
+	})
+	if err := xml2RPC(data, req); err != nil {
+		t.Error("XML2RPC conversion failed", err)
+	}
+	if req.I != 5 || req.S != "" || !req.B || req.A == nil || len(req.A) != 0 || req.P != nil || req.X != 7 {
+		t.Errorf("expected 5, \"\", true, [], nil and 7, but got %+v", req)
+	}
+
+	var values []interface{}
+	if err := xml2RPC(data, &values); err != nil {
+		t.Error("XML2RPC conversion failed", err)
+	}
+	expected := []interface{}{5, "", true, []interface{}{}, nil, 7}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("expected %#v, but got %#v", expected, values)
+	}
+
+	fault := "<methodResponse><fault><value>\n  <struct>\n    <member><name>faultCode</name><value>\n      <i4>4</i4>\n    </value></member>\n    <member><name>faultString</name><value>\n      <string></string>\n    </value></member>\n  </struct>\n</value></fault></methodResponse>"
+	err := xml2RPC(fault, &values)
+	if f, ok := err.(Fault); !ok || f.Code != 4 || f.String != "" {
+		t.Errorf("expected fault 4 with an empty string, but got %#v", err)
+	}
+}
+
+func TestXML2RPCEmptyArrayMembers(t *testing.T) {
+	for _, array := range []string{"<array><data></data></array>", "<array><data/></array>", "<array/>", "\n  <array>\n    <data>\n    </data>\n  </array>\n"} {
+		data := "<methodResponse><params><param><value><struct><member><name>items</name><value>" + array + "</value></member><member><name>names</name><value>" + array + "</value></member><member><name>subs</name><value>" + array + "</value></member></struct></value></param></params></methodResponse>"
+		req := new(struct {
+			S struct {
+				Items []int
+				Names *[]string
+				Subs  []struct{ Foo int }
+			}
+		})
+		if err := xml2RPC(data, req); err != nil {
+			t.Errorf("%q: expected err to be nil, but got %v", array, err)
+		}
+		if req.S.Items == nil || len(req.S.Items) != 0 {
+			t.Errorf("%q: expected an empty slice, but got %#v", array, req.S.Items)
+		}
+		if req.S.Names == nil || *req.S.Names == nil || len(*req.S.Names) != 0 {
+			t.Errorf("%q: expected a pointer to an empty slice, but got %#v", array, req.S.Names)
+		}
+		if req.S.Subs == nil || len(req.S.Subs) != 0 {
+			t.Errorf("%q: expected an empty slice, but got %#v", array, req.S.Subs)
+		}
+
+		m := new(struct{ M map[string][]int })
+		if err := xml2RPC(data, m); err != nil {
+			t.Errorf("%q: expected err to be nil, but got %v", array, err)
+		}
+		if items, ok := m.M["items"]; !ok || items == nil || len(items) != 0 {
+			t.Errorf("%q: expected an empty slice, but got %#v", array, m.M)
+		}
+	}
+}
+
+func TestDecodeWithExtras(t *testing.T) {
+	data := "<methodResponse><params><param><value><struct><member><name>Foo</name><value><int>42</int></value></member><member><name>Bar</name><value><string>bar</string></value></member><member><name>extra</name><value><array><data><value><int>1</int></value></data></array></value></member><member><name>Sub</name><value><struct><member><name>Foo</name><value><int>7</int></value></member><member><name>flag</name><value><boolean>1</boolean></value></member></struct></value></member></struct></value></param></params></methodResponse>"
+	var out struct {
+		S struct {
+			Foo int
+			Bar string
+			Sub struct{ Foo int }
+		}
+	}
+	extras, err := DecodeWithExtras(strings.NewReader(data), &out)
+	if err != nil {
+		t.Error("Expected err to be nil, but got:", err)
+	}
+	if out.S.Foo != 42 || out.S.Bar != "bar" || out.S.Sub.Foo != 7 {
+		t.Errorf("expected 42, bar and 7, but got %+v", out.S)
+	}
+	expected := map[string]interface{}{
+		"params[0].extra":    []interface{}{1},
+		"params[0].Sub.flag": true,
+	}
+	if !reflect.DeepEqual(extras, expected) {
+		t.Errorf("expected %v, but got %v", expected, extras)
+	}
+
+	extras, err = DecodeWithExtras(strings.NewReader(data), &struct {
+		S struct {
+			Foo, Extra interface{}
+			Bar        string
+			Sub        map[string]interface{}
+		}
+	}{})
+	if err != nil || extras != nil {
+		t.Errorf("expected no extras, but got %v, %v", extras, err)
+	}
+}
+
+func TestXML2RPCIntBooleans(t *testing.T) {
+	data := "<methodResponse><params><param><value><int>1</int></value></param><param><value><i4>0</i4></value></param></params></methodResponse>"
+	req := &struct{ A, B bool }{false, true}
+	if err := NewDecoder(Lenient()).xml2RPC(data, req); err != nil {
+		t.Error("XML2RPC conversion failed", err)
+	}
+	if !req.A || req.B {
+		t.Errorf("expected true and false, but got %v and %v", req.A, req.B)
+	}
+
+	data = "<methodResponse><params><param><value><int>2</int></value></param><param><value><i4>0</i4></value></param></params></methodResponse>"
+	if err := NewDecoder(Lenient()).xml2RPC(data, req); err == nil {
+		t.Error("expected error decoding 2 into a bool, but got nil")
+	}
+	if err := xml2RPC("<methodResponse><params><param><value><int>1</int></value></param></params></methodResponse>", &struct{ A bool }{}); err == nil {
+		t.Error("expected error decoding an int into a bool without Lenient, but got nil")
+	}
+}
+
+func TestXML2RPCSeveralTypes(t *testing.T) {
+	data := "<methodResponse><params><param><value><string>first</string><int>2</int></value></param><param><value><int>1</int> <string>second</string></value></param><param><value><struct><member><name>A</name><value><i4>3</i4><double>3.5</double></value></member></struct></value></param></params></methodResponse>"
+
+	var warnings []error
+	var req struct {
+		A interface{}
+		B int
+		C struct{ A int }
+	}
+	err := NewDecoder(WithWarningHandler(func(err error) {
+		warnings = append(warnings, err)
+	})).xml2RPC(data, &req)
+	if err != nil {
+		t.Error("XML2RPC conversion failed", err)
+	}
+	if req.A != "first" || req.B != 1 || req.C.A != 3 {
+		t.Errorf("expected the first type of each value, but got %+v", req)
+	}
+	expected := []string{
+		"params[0]: value of several types <string>, <int>, decoded as <string>",
+		"params[1]: value of several types <int>, <string>, decoded as <int>",
+		"params[2].A: value of several types <i4>, <double>, decoded as <i4>",
+	}
+	if len(warnings) != len(expected) {
+		t.Fatalf("expected %d warnings, but got %v", len(expected), warnings)
+	}
+	for i, warning := range warnings {
+		if warning.Error() != expected[i] {
+			t.Errorf("expected warning %q, but got %q", expected[i], warning)
+		}
+	}
+
+	err = NewDecoder(Strict()).xml2RPC(data, &req)
+	if fault, ok := err.(Fault); !ok || !strings.Contains(fault.String, "<string>, <int>") {
+		t.Errorf("expected fault naming the types, but got %v", err)
+	}
+}
+
+func TestTypeCount(t *testing.T) {
+	// Values of a single type aren't scanned for others, however many
+	// members or items they have.
+	tests := []struct {
+		value    string
+		expected int
+	}{
+		{"<int>1</int>", 1},
+		{"<struct><member><name>a</name><value>1</value></member><member><name>b</name><value>2</value></member></struct>", 1},
+		{"<array><data><value>1</value><value>2</value><value>3</value></data></array>", 1},
+		{"<int>1</int><string>a</string>", 2},
+		{"<array><data><value>1</value><value>2</value></data></array><struct><member><name>a</name><value>1</value></member></struct>", 2},
+	}
+	for _, test := range tests {
+		var value Value
+		if err := xml.Unmarshal([]byte("<value>"+test.value+"</value>"), &value); err != nil {
+			t.Fatal(err)
+		}
+		if n := typeCount(value); n != test.expected {
+			t.Errorf("%s: expected %d types, but got %d", test.value, test.expected, n)
+		}
+	}
+}
@@ -115,7 +115,12 @@ func execute(t *testing.T, s *rpc.Server, method string, req, res interface{}) e
 	if !s.HasMethod(method) {
 		t.Fatal("Expected to be registered:", method)
 	}
+	return call(s, method, req, res)
+}
 
+// call performs an XML-RPC call through the server s, without requiring
+// method to be registered, so that aliases can be tested.
+func call(s *rpc.Server, method string, req, res interface{}) error {
 	buf, _ := EncodeClientRequest(method, req)
 	body := bytes.NewBuffer(buf)
 	r, _ := http.NewRequest("POST", "http://localhost:8080/", body)
@@ -0,0 +1,119 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package xmlrpctest provides a stub XML-RPC server for testing clients.
+//
+// Calls are decoded and responses encoded by the xml package itself, so
+// that client tests exercise genuine wire formats:
+//
+//	s := xmlrpctest.NewStubServer(t)
+//	s.Stub("Math.Add", func(args []interface{}) (interface{}, *xml.Fault) {
+//		return args[0].(int) + args[1].(int), nil
+//	})
+//	// Point the client under test to s.URL.
+package xmlrpctest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/divan/gorilla-xmlrpc/xml"
+)
+
+// Handler handles a call to a stubbed method. It returns either the
+// result, encoded as the only parameter of the response, or a fault.
+type Handler func(args []interface{}) (interface{}, *xml.Fault)
+
+// Call is a call received by a StubServer.
+type Call struct {
+	Method string
+	Args   []interface{}
+}
+
+// StubServer is an HTTP server answering XML-RPC calls with stubs.
+// Calls to methods without a stub are answered with a -32601 fault and
+// recorded for assertion.
+type StubServer struct {
+	*httptest.Server
+	t testing.TB
+
+	mu         sync.Mutex
+	stubs      map[string]Handler
+	calls      []Call
+	unexpected []Call
+}
+
+// NewStubServer starts a StubServer, which is closed when the test ends.
+func NewStubServer(t testing.TB) *StubServer {
+	s := &StubServer{
+		t:     t,
+		stubs: make(map[string]Handler),
+	}
+	s.Server = httptest.NewServer(s)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Stub makes the server answer calls to method with handler.
+func (s *StubServer) Stub(method string, handler Handler) {
+	s.mu.Lock()
+	s.stubs[method] = handler
+	s.mu.Unlock()
+}
+
+// Calls returns all the calls received so far, in order.
+func (s *StubServer) Calls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Call(nil), s.calls...)
+}
+
+// Unexpected returns the calls received so far for methods without a stub.
+func (s *StubServer) Unexpected() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Call(nil), s.unexpected...)
+}
+
+// ServeHTTP decodes a call and answers it with the matching stub.
+func (s *StubServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+
+	var args []interface{}
+	method, err := xml.DecodeServerRequest(r.Body, &args)
+	if err != nil {
+		s.t.Errorf("xmlrpctest: malformed request: %v", err)
+		fault := xml.FaultDecode
+		fault.String += ": " + err.Error()
+		w.Write(xml.EncodeFault(fault))
+		return
+	}
+
+	call := Call{Method: method, Args: args}
+	s.mu.Lock()
+	s.calls = append(s.calls, call)
+	handler, ok := s.stubs[method]
+	if !ok {
+		s.unexpected = append(s.unexpected, call)
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		w.Write(xml.EncodeFault(xml.FaultMethodNotFound))
+		return
+	}
+
+	result, fault := handler(args)
+	if fault != nil {
+		w.Write(xml.EncodeFault(*fault))
+		return
+	}
+	body, err := xml.EncodeServerResponse(&struct{ Result interface{} }{result})
+	if err != nil {
+		s.t.Errorf("xmlrpctest: can't encode result of %s: %v", method, err)
+	}
+	w.Write(body)
+}
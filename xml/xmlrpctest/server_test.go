@@ -0,0 +1,69 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xmlrpctest
+
+import (
+	"bytes"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/divan/gorilla-xmlrpc/xml"
+)
+
+func call(t *testing.T, url, method string, args, reply interface{}) error {
+	buf, err := xml.EncodeClientRequest(method, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Post(url, "text/xml", bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	return xml.DecodeClientResponse(resp.Body, reply)
+}
+
+type Person struct {
+	Name string
+	Age  int
+}
+
+func TestStubServer(t *testing.T) {
+	s := NewStubServer(t)
+	s.Stub("People.Greet", func(args []interface{}) (interface{}, *xml.Fault) {
+		person := args[0].(map[string]interface{})
+		return "Hello, " + person["Name"].(string), nil
+	})
+	s.Stub("People.Delete", func(args []interface{}) (interface{}, *xml.Fault) {
+		return nil, &xml.Fault{Code: 403, String: "Forbidden"}
+	})
+
+	var reply struct{ Message string }
+	if err := call(t, s.URL, "People.Greet", &struct{ P Person }{Person{"Johnny", 33}}, &reply); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if reply.Message != "Hello, Johnny" {
+		t.Errorf("Wrong response: %v.", reply.Message)
+	}
+
+	err := call(t, s.URL, "People.Delete", &struct{ ID int }{1}, &reply)
+	if fault, ok := err.(xml.Fault); !ok || fault.Code != 403 {
+		t.Errorf("expected fault 403, but got %v", err)
+	}
+
+	err = call(t, s.URL, "People.Update", &struct{ ID int }{2}, &reply)
+	if fault, ok := err.(xml.Fault); !ok || fault.Code != -32601 {
+		t.Errorf("expected fault -32601, but got %v", err)
+	}
+
+	expected := []Call{{"People.Update", []interface{}{2}}}
+	if unexpected := s.Unexpected(); !reflect.DeepEqual(unexpected, expected) {
+		t.Errorf("wrong unexpected calls: %v", unexpected)
+	}
+	if calls := s.Calls(); len(calls) != 3 || calls[0].Method != "People.Greet" {
+		t.Errorf("wrong calls: %v", calls)
+	}
+}